@@ -0,0 +1,179 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+type aggregateColumn struct {
+	function string
+	field    string
+	alias    string
+}
+
+// Collection.Aggregate 的構造器， 在 collection 的過濾條件上用 sql 計算
+type Aggregation struct {
+	collection *Collection
+	columns    []aggregateColumn
+	groups     []string
+	having     []string
+}
+
+// 一個分組的結果， 用 alias 取值
+type AggregateRow map[string]interface{}
+
+func (r AggregateRow) Get(alias string) interface{} {
+	return r[alias]
+}
+
+func (r AggregateRow) Int(alias string) int64 {
+	return ConvertToInt64(r[alias])
+}
+
+func (r AggregateRow) Float(alias string) float64 {
+	return ConvertToFloat64(r[alias])
+}
+
+func (r AggregateRow) String(alias string) string {
+	return ConvertToString(r[alias])
+}
+
+// 例如 Aggregate().Avg("age", "avg_age").GroupBy("city").Fetch()
+func (e *Collection) Aggregate() *Aggregation {
+	return &Aggregation{collection: e}
+}
+
+func (a *Aggregation) add(function string, field string, alias string) *Aggregation {
+	a.columns = append(a.columns, aggregateColumn{function: function, field: strings.ToLower(field), alias: alias})
+	return a
+}
+
+// field 為空時是 count(*)
+func (a *Aggregation) Count(field string, alias string) *Aggregation {
+	return a.add("count", field, alias)
+}
+
+func (a *Aggregation) Sum(field string, alias string) *Aggregation {
+	return a.add("sum", field, alias)
+}
+
+func (a *Aggregation) Avg(field string, alias string) *Aggregation {
+	return a.add("avg", field, alias)
+}
+
+func (a *Aggregation) Min(field string, alias string) *Aggregation {
+	return a.add("min", field, alias)
+}
+
+func (a *Aggregation) Max(field string, alias string) *Aggregation {
+	return a.add("max", field, alias)
+}
+
+// eav 字段按當前 locale 分組， 結果中的 key 是字段名
+func (a *Aggregation) GroupBy(fields ...string) *Aggregation {
+	for _, field := range fields {
+		a.groups = append(a.groups, strings.ToLower(field))
+	}
+	return a
+}
+
+// 條件中用 alias， 例如 Having("avg_age > ?", 30)
+func (a *Aggregation) Having(condition string, values ...interface{}) *Aggregation {
+	a.having = append(a.having, a.collection.Connection.Expr(condition, values...))
+	return a
+}
+
+// 聚合在 collection 的副本上組裝， 默認 scope、 join 和用到的字段不影響 collection
+func (e *Collection) cloneForAggregate() *Collection {
+	clone := *e
+	if dbselect, ok := e.DbSelect.(*DBSelect); ok {
+		clone.DbSelect = dbselect.clone()
+	}
+	clone.UsedFields = make(map[string]bool, len(e.UsedFields))
+	for key, value := range e.UsedFields {
+		clone.UsedFields[key] = value
+	}
+	return &clone
+}
+
+// 組裝 sql， 只 join 過濾、排序、分組和計算用到的 eav 字段
+func (a *Aggregation) GetSql() (string, error) {
+	e := a.collection.cloneForAggregate()
+	e.applyDefaultScopes()
+	if e.BuildError != nil {
+		return "", e.BuildError
+	}
+	if len(a.columns) == 0 {
+		return "", fmt.Errorf("no aggregate columns")
+	}
+	columns := make([]string, 0, len(a.groups)+len(a.columns))
+	groups := make([]string, 0, len(a.groups))
+	for _, field := range a.groups {
+		column, ok := e.filterColumn(field)
+		if !ok {
+			return "", fmt.Errorf("unknown group field %s", field)
+		}
+		e.useField(field)
+		columns = append(columns, column+" as "+field)
+		groups = append(groups, column)
+	}
+	for _, item := range a.columns {
+		if !isSqlIdentifier(item.alias) {
+			return "", fmt.Errorf("invalid aggregate alias %s", item.alias)
+		}
+		if item.field == "" || item.field == "*" {
+			columns = append(columns, item.function+"(*) as "+item.alias)
+			continue
+		}
+		column, ok := e.filterColumn(item.field)
+		if !ok {
+			return "", fmt.Errorf("unknown aggregate field %s", item.field)
+		}
+		e.useField(item.field)
+		columns = append(columns, item.function+"("+column+") as "+item.alias)
+	}
+
+	eavFields := make(map[string]Field)
+	for key, field := range e.Model.GetEavFields() {
+		if e.UsedFields[key] {
+			eavFields[key] = field
+		}
+	}
+	table, _ := e.fromTable(eavFields)
+	dbselect, _ := e.DbSelect.(*DBSelect)
+	dbselect.From(table, "e", nil).Limit(0).Offset(0)
+	dbselect._columns = columns
+	dbselect._group = groups
+	dbselect._having = a.having
+	dbselect._order = groups
+	return dbselect.Assemble()
+}
+
+// 每個分組一行， 沒有 GroupBy 時一行
+func (a *Aggregation) Fetch() ([]AggregateRow, error) {
+	rows := make([]AggregateRow, 0)
+	e := a.collection
+	e._transaction(func() {
+		sql, err := a.GetSql()
+		if err != nil {
+			panic(err)
+		}
+		for _, row := range e.Connection.Fetch(sql) {
+			rows = append(rows, AggregateRow(row))
+		}
+	})
+	return rows, e.LastError
+}
+
+// alias 只能是字母、數字和下劃線
+func isSqlIdentifier(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range value {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
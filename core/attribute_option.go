@@ -0,0 +1,242 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// select / multiselect 字段的選項， labels 按 locale 翻譯
+// EntityType: 主表名字， 跟 GetTableName 一樣
+type AttributeOption struct {
+	OptionId      uint64
+	EntityType    string
+	AttributeCode string
+	SortOrder     int
+	Labels        map[string]string // locale => label
+}
+
+func (o AttributeOption) GetLabel(locale string, defaultLocale string) string {
+	if label, ok := o.Labels[locale]; ok && label != "" {
+		return label
+	}
+	return o.Labels[defaultLocale]
+}
+
+var attributeOptionCache = make(map[string][]AttributeOption)
+var attributeOptionCacheLock sync.RWMutex
+
+func attributeOptionCacheKey(connectionName string, entityType string, code string) string {
+	return connectionName + "|" + entityType + "|" + code
+}
+
+func ClearAttributeOptionCache() {
+	attributeOptionCacheLock.Lock()
+	defer attributeOptionCacheLock.Unlock()
+	attributeOptionCache = make(map[string][]AttributeOption)
+}
+
+func clearAttributeOptionCache(connectionName string, entityType string, code string) {
+	attributeOptionCacheLock.Lock()
+	defer attributeOptionCacheLock.Unlock()
+	delete(attributeOptionCache, attributeOptionCacheKey(connectionName, entityType, code))
+}
+
+// 按 sort_order 排序， 結果會緩存
+func GetAttributeOptions(connectionName string, entityType string, code string) ([]AttributeOption, error) {
+	key := attributeOptionCacheKey(connectionName, entityType, code)
+	attributeOptionCacheLock.RLock()
+	options, ok := attributeOptionCache[key]
+	attributeOptionCacheLock.RUnlock()
+	if ok {
+		return options, nil
+	}
+
+	err := recoverError(func() {
+		connection := newConnection(connectionName)
+		rows := connection.Fetch(connection.Expr("select * from eav_attribute_option where entity_type = ? and attribute_code = ? order by sort_order, option_id", entityType, code))
+		labels := connection.Fetch(connection.Expr("select v.* from eav_attribute_option_value as v inner join eav_attribute_option as o on o.option_id = v.option_id where o.entity_type = ? and o.attribute_code = ?", entityType, code))
+		options = make([]AttributeOption, 0, len(rows))
+		for _, row := range rows {
+			option := AttributeOption{
+				OptionId:      ConvertToUint64(row["option_id"]),
+				EntityType:    entityType,
+				AttributeCode: code,
+				SortOrder:     ConvertToInt(row["sort_order"]),
+				Labels:        make(map[string]string),
+			}
+			for _, label := range labels {
+				if ConvertToUint64(label["option_id"]) == option.OptionId {
+					option.Labels[ConvertToString(label["locale"])] = ConvertToString(label["label"])
+				}
+			}
+			options = append(options, option)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	attributeOptionCacheLock.Lock()
+	attributeOptionCache[key] = options
+	attributeOptionCacheLock.Unlock()
+	return options, nil
+}
+
+// 新增或者更新， Labels 會整個替換
+func SaveAttributeOption(connectionName string, option *AttributeOption) error {
+	option.AttributeCode = strings.ToLower(option.AttributeCode)
+	if option.EntityType == "" || option.AttributeCode == "" {
+		return fmt.Errorf("entity type and attribute code are required")
+	}
+	err := transaction(connectionName, func(connection DBConnectionInterface) {
+		data := map[string]interface{}{"entity_type": option.EntityType, "attribute_code": option.AttributeCode, "sort_order": option.SortOrder}
+		if option.OptionId == 0 {
+			option.OptionId = uint64(connection.Insert("eav_attribute_option", data))
+		} else {
+			connection.Update("eav_attribute_option", data, connection.Expr("option_id = ?", option.OptionId))
+		}
+		connection.Delete("eav_attribute_option_value", connection.Expr("option_id = ?", option.OptionId))
+		locales := make([]string, 0, len(option.Labels))
+		for locale := range option.Labels {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+		labels := make([]map[string]interface{}, 0, len(locales))
+		for _, locale := range locales {
+			labels = append(labels, map[string]interface{}{"option_id": option.OptionId, "locale": locale, "label": option.Labels[locale]})
+		}
+		connection.InsertMulti("eav_attribute_option_value", labels)
+	})
+	clearAttributeOptionCache(connectionName, option.EntityType, option.AttributeCode)
+	return err
+}
+
+// 實體中保存的 option id 不會刪除
+func DeleteAttributeOption(connectionName string, entityType string, code string, optionId uint64) error {
+	err := recoverError(func() {
+		connection := newConnection(connectionName)
+		connection.Delete("eav_attribute_option", connection.Expr("option_id = ? and entity_type = ? and attribute_code = ?", optionId, entityType, strings.ToLower(code)))
+	})
+	clearAttributeOptionCache(connectionName, entityType, strings.ToLower(code))
+	return err
+}
+
+// 把 select / multiselect 的值拆分為 option id
+func splitOptionIds(value interface{}) []string {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(ConvertToString(value), ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// select / multiselect 字段的 label， 用 model 的 Locale， 沒有翻譯時用 DefaultLocale， multiselect 用 ", " 連接
+// 其他字段返回 GetData 的字符串， 選項加載失敗時設置 LastError 並返回 GetData 的字符串
+func (e *Basictablemodel) GetDataLabel(field string) string {
+	field = strings.ToLower(field)
+	value := e.GetData(field)
+	def, ok := e.GetTableFields()[field]
+	if !ok || (def.Input != InputSelect && def.Input != InputMultiselect) {
+		return ConvertToString(value)
+	}
+	options, err := GetAttributeOptions(e.GetConnectionName(), e.Model.GetTableName(), field)
+	if err != nil {
+		e.LastError = err
+		return ConvertToString(value)
+	}
+	labels := make([]string, 0)
+	for _, id := range splitOptionIds(value) {
+		for _, option := range options {
+			if ConvertToString(option.OptionId) == id {
+				labels = append(labels, option.GetLabel(e.GetLocale(), e.GetDefaultLocale()))
+				break
+			}
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+func (e *Collection) getOptionField(field string) (Field, error) {
+	def, ok := e.Model.GetTableFields()[strings.ToLower(field)]
+	if !ok || (def.Input != InputSelect && def.Input != InputMultiselect) {
+		return def, fmt.Errorf("%s is not a select or multiselect field", field)
+	}
+	return def, nil
+}
+
+// select 字段 join 選項的 label， 返回 label 的表達式
+func (e *Collection) joinOptionLabel(field string) string {
+	alias := "o_" + field
+	e.DbSelect.LeftJoin(alias, "eav_attribute_option_value", e.Connection.Expr(alias+".option_id = e."+field+" and "+alias+".locale = ?", e.Model.GetLocale()), nil)
+	e.DbSelect.LeftJoin(alias+"_default", "eav_attribute_option_value", e.Connection.Expr(alias+"_default.option_id = e."+field+" and "+alias+"_default.locale = ?", e.Model.GetDefaultLocale()), nil)
+	return "ifnull(" + alias + ".label, " + alias + "_default.label)"
+}
+
+// 按選項的 label 過濾， 格式跟 AddFieldToFilter 一樣： {"like": "Re%"}， multiselect 任意一個選項符合即可
+func (e *Collection) AddOptionLabelFilter(field string, conditions map[string]interface{}) CollectionInterface {
+	field = strings.ToLower(field)
+	e.useField(field)
+	def, err := e.getOptionField(field)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	label := "ifnull(ol.label, od.label)"
+	if def.Input == InputSelect {
+		label = e.joinOptionLabel(field)
+	}
+	operators := make([]string, 0, len(conditions))
+	for operator := range conditions {
+		operators = append(operators, operator)
+	}
+	sort.Strings(operators)
+	sqls := make([]string, 0)
+	values := make([]interface{}, 0)
+	for _, operator := range operators {
+		if err := checkMatchField(operator, field, false); err != nil {
+			e.BuildError = err
+			return e
+		}
+		conditionSql, conditionValues, err := filterConditionSql(label, operator, conditions[operator])
+		if err != nil {
+			e.BuildError = err
+			return e
+		}
+		sqls = append(sqls, conditionSql)
+		values = append(values, conditionValues...)
+	}
+	if len(sqls) == 0 {
+		return e
+	}
+	sql := strings.Join(sqls, " and ")
+	if def.Input == InputMultiselect {
+		sql = "exists (select 1 from eav_attribute_option as o" +
+			" left join eav_attribute_option_value as ol on ol.option_id = o.option_id and ol.locale = ?" +
+			" left join eav_attribute_option_value as od on od.option_id = o.option_id and od.locale = ?" +
+			" where o.entity_type = ? and o.attribute_code = ? and find_in_set(o.option_id, e." + field + ") and " + sql + ")"
+		values = append([]interface{}{e.Model.GetLocale(), e.Model.GetDefaultLocale(), e.Model.GetModel().GetTableName(), field}, values...)
+	}
+	e.DbSelect.Where(e.Connection.Expr(sql, values...))
+	return e
+}
+
+// 按 select 字段選項的 label 排序
+func (e *Collection) AddOptionLabelOrder(field string, dir string) CollectionInterface {
+	field = strings.ToLower(field)
+	e.useField(field)
+	def, err := e.getOptionField(field)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	if def.Input != InputSelect {
+		e.BuildError = fmt.Errorf("cannot order by multiselect field %s", field)
+		return e
+	}
+	e.DbSelect.Order(e.joinOptionLabel(field) + " " + dir)
+	return e
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 實體表保存 attribute set 的字段， 需要在 GetTableFields 中定義
+const AttributeSetField = "attribute_set_id"
+
+// attribute set 分配給實體的 eav 字段 (GetTableFields 或者 eav_attribute 中的字段)， 用 group 組織表單
+type AttributeSet struct {
+	AttributeSetId uint64
+	EntityType     string
+	Name           string
+	Groups         []AttributeGroup
+}
+
+type AttributeGroup struct {
+	AttributeGroupId uint64
+	Name             string
+	SortOrder        int
+	Attributes       []string // attribute code， 按順序
+}
+
+// 返回所有分配的 attribute code
+func (s *AttributeSet) GetAttributeCodes() map[string]bool {
+	codes := make(map[string]bool)
+	for _, group := range s.Groups {
+		for _, code := range group.Attributes {
+			codes[code] = true
+		}
+	}
+	return codes
+}
+
+func (s *AttributeSet) HasAttribute(code string) bool {
+	return s.GetAttributeCodes()[code]
+}
+
+var attributeSetCache = make(map[string]*AttributeSet)
+var attributeSetCacheLock sync.RWMutex
+
+func attributeSetCacheKey(connectionName string, attributeSetId uint64) string {
+	return fmt.Sprintf("%s|%d", connectionName, attributeSetId)
+}
+
+func ClearAttributeSetCache() {
+	attributeSetCacheLock.Lock()
+	defer attributeSetCacheLock.Unlock()
+	attributeSetCache = make(map[string]*AttributeSet)
+}
+
+// 結果會緩存， groups 和 attributes 按 sort_order 排序
+func GetAttributeSet(connectionName string, attributeSetId uint64) (*AttributeSet, error) {
+	return getAttributeSet(nil, connectionName, attributeSetId)
+}
+
+// connection 不為空時用它查詢， 例如 model 所在的事務， 事務中讀取的結果不緩存
+func getAttributeSet(connection DBConnectionInterface, connectionName string, attributeSetId uint64) (*AttributeSet, error) {
+	key := attributeSetCacheKey(connectionName, attributeSetId)
+	attributeSetCacheLock.RLock()
+	set, ok := attributeSetCache[key]
+	attributeSetCacheLock.RUnlock()
+	if ok {
+		return set, nil
+	}
+
+	err := recoverError(func() {
+		if connection == nil {
+			connection = newConnection(connectionName)
+		}
+		row := connection.FetchRow(connection.Expr("select * from eav_attribute_set where attribute_set_id = ?", attributeSetId))
+		if row == nil {
+			panic(fmt.Errorf("attribute set %d: %w", attributeSetId, ErrNotFound))
+		}
+		set = &AttributeSet{AttributeSetId: attributeSetId, EntityType: ConvertToString(row["entity_type"]), Name: ConvertToString(row["name"]), Groups: make([]AttributeGroup, 0)}
+		groups := connection.Fetch(connection.Expr("select * from eav_attribute_group where attribute_set_id = ? order by sort_order, attribute_group_id", attributeSetId))
+		attributes := connection.Fetch(connection.Expr("select * from eav_entity_attribute where attribute_set_id = ? order by sort_order, attribute_code", attributeSetId))
+		for _, groupRow := range groups {
+			group := AttributeGroup{AttributeGroupId: ConvertToUint64(groupRow["attribute_group_id"]), Name: ConvertToString(groupRow["name"]), SortOrder: ConvertToInt(groupRow["sort_order"]), Attributes: make([]string, 0)}
+			for _, attribute := range attributes {
+				if ConvertToUint64(attribute["attribute_group_id"]) == group.AttributeGroupId {
+					group.Attributes = append(group.Attributes, ConvertToString(attribute["attribute_code"]))
+				}
+			}
+			set.Groups = append(set.Groups, group)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if connection != nil && inTransaction(connection) {
+		return set, nil
+	}
+	attributeSetCacheLock.Lock()
+	attributeSetCache[key] = set
+	attributeSetCacheLock.Unlock()
+	return set, nil
+}
+
+// 新增或者更新， groups 和分配的字段會整個替換
+func SaveAttributeSet(connectionName string, set *AttributeSet) error {
+	if set.EntityType == "" || set.Name == "" {
+		return fmt.Errorf("entity type and name are required")
+	}
+	err := transaction(connectionName, func(connection DBConnectionInterface) {
+		data := map[string]interface{}{"entity_type": set.EntityType, "name": set.Name}
+		if set.AttributeSetId == 0 {
+			set.AttributeSetId = uint64(connection.Insert("eav_attribute_set", data))
+		} else {
+			connection.Update("eav_attribute_set", data, connection.Expr("attribute_set_id = ?", set.AttributeSetId))
+		}
+		connection.Delete("eav_attribute_group", connection.Expr("attribute_set_id = ?", set.AttributeSetId))
+		for index, group := range set.Groups {
+			groupId := connection.Insert("eav_attribute_group", map[string]interface{}{"attribute_set_id": set.AttributeSetId, "name": group.Name, "sort_order": group.SortOrder})
+			set.Groups[index].AttributeGroupId = uint64(groupId)
+			attributes := make([]map[string]interface{}, 0, len(group.Attributes))
+			for sortOrder, code := range group.Attributes {
+				attributes = append(attributes, map[string]interface{}{"attribute_set_id": set.AttributeSetId, "attribute_group_id": groupId, "attribute_code": code, "sort_order": sortOrder})
+			}
+			connection.InsertMulti("eav_entity_attribute", attributes)
+		}
+	})
+	attributeSetCacheLock.Lock()
+	delete(attributeSetCache, attributeSetCacheKey(connectionName, set.AttributeSetId))
+	attributeSetCacheLock.Unlock()
+	return err
+}
+
+// 實體的 attribute_set_id 會設置為 null
+func DeleteAttributeSet(connectionName string, attributeSetId uint64) error {
+	err := recoverError(func() {
+		connection := newConnection(connectionName)
+		connection.Delete("eav_attribute_set", connection.Expr("attribute_set_id = ?", attributeSetId))
+	})
+	attributeSetCacheLock.Lock()
+	delete(attributeSetCache, attributeSetCacheKey(connectionName, attributeSetId))
+	attributeSetCacheLock.Unlock()
+	return err
+}
+
+func (e *Basictablemodel) isAttributeSetEav() bool {
+	if m, ok := interface{}(e.Model).(IsAttributeSetEavInterface); ok {
+		return m.IsAttributeSetEav()
+	}
+	return false
+}
+
+// 返回實體的 attribute set， 沒有設置時返回 nil， attribute set 不存在時返回 ErrNotFound
+func (e *Basictablemodel) GetAttributeSet() (*AttributeSet, error) {
+	if !e.isAttributeSetEav() {
+		return nil, nil
+	}
+	attributeSetId := ConvertToUint64(e.GetData(AttributeSetField))
+	if attributeSetId == 0 {
+		return nil, nil
+	}
+	return getAttributeSet(e.GetConnection(), e.GetConnectionName(), attributeSetId)
+}
+
+// 只加載 attribute set 的字段， collection 的元素都是這個 attribute set
+func (e *Collection) SetAttributeSet(attributeSetId uint64) CollectionInterface {
+	e.Model.SetData(AttributeSetField, attributeSetId)
+	e.DbSelect.Where(e.Connection.Expr("e."+AttributeSetField+" = ?", attributeSetId))
+	return e
+}
@@ -5,6 +5,7 @@ import (
 
 	"fmt"
 
+	driver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -38,6 +39,10 @@ func InitDB(dsn string, connectionName string) (*gorm.DB, error) {
 	if checkDb(connectionName) {
 		return _db, nil
 	}
+	dsn, err := withGroupConcatMaxLen(dsn)
+	if err != nil {
+		return nil, err
+	}
 	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
@@ -53,6 +58,26 @@ func InitDB(dsn string, connectionName string) (*gorm.DB, error) {
 	addConnect(connectionName, db)
 	return db, nil
 }
+
+// 多值 eav 字段用 group_concat 加載， 默認的 group_concat_max_len (1024) 會截斷長列表
+const groupConcatMaxLen = "67108864"
+
+// dsn 沒有設置 group_concat_max_len 時加上， 驅動在每個新連接上執行 set
+func withGroupConcatMaxLen(dsn string) (string, error) {
+	config, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := config.Params["group_concat_max_len"]; ok {
+		return dsn, nil
+	}
+	if config.Params == nil {
+		config.Params = make(map[string]string)
+	}
+	config.Params["group_concat_max_len"] = groupConcatMaxLen
+	return config.FormatDSN(), nil
+}
+
 func CloseDb(connectionName string) {
 	_db := GetConnection(connectionName)
 	if checkDb(connectionName) {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -28,22 +29,44 @@ type CollectionInterface interface {
 	AddOrder(order string, dir string) CollectionInterface
 	Create() Basictablemodelinterface
 	GetLastError() error
+	With(...string) CollectionInterface                                                                                                // 批量加載關聯， 例如 With("addresses", "orders.items")
+	AddRelationFilter(relation string, filters map[string]map[string]interface{}) CollectionInterface                                  // 有至少一個符合 filters 的關聯
+	AddRelationCountFilter(relation string, filters map[string]map[string]interface{}, operator string, count int) CollectionInterface // 符合 filters 的關聯數量， 例如 ">=", 3
+	AddSubtreeFilter(rootId interface{}, includeRoot bool) CollectionInterface                                                         // 樹形 model 的子樹
+	SetAttributeSet(attributeSetId uint64) CollectionInterface                                                                         // 只加載 attribute set 的 eav 字段
+	AddOptionLabelFilter(field string, conditions map[string]interface{}) CollectionInterface                                          // select / multiselect 字段按選項 label 過濾
+	AddOptionLabelOrder(field string, dir string) CollectionInterface                                                                  // select 字段按選項 label 排序
+	SetEavScopes(...EavScope) CollectionInterface                                                                                      // 例如 store, website， 最後是 global
+	SetEavScope(EavScope) CollectionInterface                                                                                          // 用 ResolveEavScopes 展開， 見 RegisterEavScopeParent
+	SetEavLoadStrategy(strategy string) CollectionInterface                                                                            // EavLoadStrategyJoin 或者 EavLoadStrategyPivot， 覆蓋 model 的設置
+	Filter(expr FilterExpr) CollectionInterface                                                                                        // 條件樹， 例如 Or(FilterField("age").Gt(18), FilterField("name").Like("jo%"))
+	ApplyQuery(values url.Values, options QueryOptions) error                                                                          // 例如 ?filter[age][gte]=18&sort=-created_at&page[size]=20
+	Scope(name string, args ...interface{}) CollectionInterface                                                                        // BasicModelScopesInterface 的命名 scope
+	WithoutDefaultScopes(names ...string) CollectionInterface                                                                          // 沒有參數時去掉全部默認 scope
+	Aggregate() *Aggregation                                                                                                           // Count, Sum, Avg, Min, Max, GroupBy, Having
+	SetFallbackLocales(...string) CollectionInterface                                                                                  // 例如 de-AT 沒有值時用 de-DE， 最後是 DefaultLocale
 }
 
 type Collection struct {
-	Elems              []Basictablemodelinterface
-	Connection         DBConnectionInterface
-	DbSelect           DBSelectInterface
-	IsLoad             bool
-	IsSizeLoad         bool
-	Size               int
-	PageSize           int
-	PageLength         int
-	Page               int
-	Factory            func() Basictablemodelinterface
-	Model              Basictablemodelinterface
-	ColumnsOfMaintable []string
-	LastError          error
+	Elems                []Basictablemodelinterface
+	Connection           DBConnectionInterface
+	DbSelect             DBSelectInterface
+	IsLoad               bool
+	IsSizeLoad           bool
+	Size                 int
+	PageSize             int
+	PageLength           int
+	Page                 int
+	Factory              func() Basictablemodelinterface
+	Model                Basictablemodelinterface
+	ColumnsOfMaintable   []string
+	LastError            error
+	Relations            []string
+	BuildError           error // 組裝 select 時的錯誤， 在 Load 和 GetSize 時返回
+	EavLoadStrategy      string
+	UsedFields           map[string]bool // 過濾和排序用到的字段
+	removedScopes        removedScopes
+	defaultScopesApplied bool
 }
 
 func (e *Collection) GetLastError() error {
@@ -99,18 +122,42 @@ func (e *Collection) _transaction(callback func()) {
 func (e *Collection) Load() CollectionInterface {
 	if !e.IsLoad {
 		e._transaction(func() {
+			e.applyDefaultScopes()
+			if e.BuildError != nil {
+				panic(e.BuildError)
+			}
+			eavFields, lazyFields := e.splitEavFields(e.Model.GetEavFields())
+			table, pivot := e.fromTable(eavFields)
+			// flat 表已經有所有 eav 字段， 直接選擇， 不需要延遲加載
+			flat := len(lazyFields) > 0 && table == FlatTableName(e.Model.GetTableName(), e.Model.GetLocale())
+			if flat {
+				eavFields, lazyFields = e.Model.GetEavFields(), nil
+			}
+			_, isEavModel := e.Model.GetModel().(EavModelInterface)
 			columns := make(map[string]string)
 			if len(e.ColumnsOfMaintable) > 0 {
+				// 總是選擇主鍵， 延遲加載和 pivot 需要
+				primaryField := e.Model.GetPrimaryFieldName()
+				columns[primaryField] = primaryField
+				allEavFields := e.Model.GetEavFields()
 				for _, value := range e.ColumnsOfMaintable {
+					// pivot 時從主表選擇， eav 字段和它的 locale 字段由 loadPivotEavValues 加載
+					if _, ok := allEavFields[value]; ok && pivot {
+						continue
+					}
 					columns[value] = value
+					if _, ok := eavFields[value]; ok && !isEavModel {
+						columns[eavLocaleColumnPrefix+value] = eavLocaleColumnPrefix + value
+					}
+				}
+				if flat {
+					for key := range eavFields {
+						columns[key] = key
+						columns[eavLocaleColumnPrefix+key] = eavLocaleColumnPrefix + key
+					}
 				}
 			}
-			eavFields := e.Model.GetEavFields()
-			if len(eavFields) == 0 {
-				e.DbSelect.From(e.Model.GetTableName(), "e", columns)
-			} else {
-				e.DbSelect.From(e.Model.GetResourceModel().GetEavAsTable(), "e", columns)
-			}
+			e.DbSelect.From(table, "e", columns)
 			e.DbSelect.Offset((e.Page - 1) * e.PageSize)
 			if e.PageSize > 0 {
 				e.DbSelect.Limit(e.PageSize)
@@ -118,8 +165,12 @@ func (e *Collection) Load() CollectionInterface {
 
 			sql, _ := e.DbSelect.Assemble()
 			rows := e.Connection.Fetch(sql)
+			if pivot {
+				loadPivotEavValues(e.Model, rows, eavFields)
+			}
 			for _, row := range rows {
 				model := e.Factory().Init()
+				model.SetFallbackLocales(e.Model.GetFallbackLocales()...).SetEavScopes(e.Model.GetEavScopes()...)
 				model.GetConnection().SetDb(e.Connection.GetDb())
 				model.GetResourceModel().LoadDbData(row)
 				if m, ok := interface{}(model.GetModel()).(BasicModelLoadInterface); ok {
@@ -127,6 +178,17 @@ func (e *Collection) Load() CollectionInterface {
 				}
 				e.Elems = append(e.Elems, model)
 			}
+			if len(lazyFields) > 0 && len(e.Elems) > 0 {
+				loader := &lazyEavLoader{elems: e.Elems, fields: lazyFields}
+				for _, elem := range e.Elems {
+					if m, ok := elem.(*Basictablemodel); ok {
+						m.lazyEav = loader
+					}
+				}
+			}
+			for _, name := range e.Relations {
+				loadRelation(e.Elems, name)
+			}
 			e.IsLoad = true
 		})
 
@@ -134,9 +196,33 @@ func (e *Collection) Load() CollectionInterface {
 	return e
 }
 
+// 沒有 eav 字段時是主表， 否則見 getEavTable
+func (e *Collection) fromTable(eavFields map[string]Field) (string, bool) {
+	if len(e.Model.GetEavFields()) == 0 {
+		return e.Model.GetTableName(), false
+	}
+	return e.getEavTable(eavFields)
+}
+
+// 有效的 flat 表， pivot 時是主表 (第二個返回值是 true)， 否則 join eavFields 的 value 表
+func (e *Collection) getEavTable(eavFields map[string]Field) (string, bool) {
+	if m, ok := e.Model.(*Basictablemodel); ok {
+		if flat := m.getFreshFlatTable(); flat != "" {
+			return flat, false
+		}
+	}
+	if e.isPivotLoad() {
+		return e.Model.GetTableName(), true
+	}
+	return e.Model.GetResourceModel().GetEavAsTableForFields(eavFields), false
+}
+
 func (e *Collection) GetSize() int {
 	if !e.IsSizeLoad {
 		e._transaction(func() {
+			if e.BuildError != nil {
+				panic(e.BuildError)
+			}
 			e.Load()
 			dbselect, _ := e.DbSelect.(*DBSelect)
 			clonedbselectObj := *dbselect
@@ -189,6 +275,10 @@ func (e *Collection) reset() CollectionInterface {
 	e.Elems = make([]Basictablemodelinterface, 0)
 	e.Size = 0
 	e.ColumnsOfMaintable = make([]string, 0)
+	e.Relations = make([]string, 0)
+	e.BuildError = nil
+	e.UsedFields = make(map[string]bool)
+	e.defaultScopesApplied = false
 	e.Page = 1
 	return e
 }
@@ -223,26 +313,25 @@ func (e *Collection) AddFieldToFilter(values map[string]map[string]interface{})
 		sql += "("
 		for field, fieldcondition := range values {
 			sql += "("
+			e.useField(field)
 			for condition, value := range fieldcondition {
+				column := field
 				model, ok := e.Model.GetModel().(CollectionFieldInterface)
 				if ok {
 					field1 := model.AddJoinField(e, field)
 					if field1 == "" {
-						field = "e." + field
+						column = "e." + field
 					} else {
-						field = field1
+						column = field1
 					}
 				}
-				if strings.ToLower(condition) == "in" {
-					sql += "(" + field + " " + condition + " (?)" + ") and"
-				} else if strings.ToLower(condition) == "not in" {
-					sql += "(" + field + " " + condition + " (?)" + ") and"
-				} else if strings.ToLower(condition) == "null" || strings.ToLower(condition) == "not null" {
-					sql += "(" + field + " is " + condition + ")" + " and"
-				} else {
-					sql += "(" + field + " " + condition + " ?" + ") and"
+				conditionSql, conditionValues, err := e.fieldConditionSql(field, column, condition, value)
+				if err != nil {
+					e.BuildError = err
+					return e
 				}
-				fieldValues = append(fieldValues, value)
+				sql += conditionSql + " and"
+				fieldValues = append(fieldValues, conditionValues...)
 			}
 			sql = strings.TrimSuffix(sql, "and") + ") or"
 		}
@@ -264,26 +353,25 @@ func (e *Collection) AddFieldToFilterAdvanced(values map[string][]map[string]int
 			sql += "("
 			for _, fieldcondition := range fieldconditions {
 				sql += "("
+				e.useField(field)
 				for condition, value := range fieldcondition {
+					column := field
 					model, ok := e.Model.GetModel().(CollectionFieldInterface)
 					if ok {
 						field1 := model.AddJoinField(e, field)
 						if field1 == "" {
-							field = "e." + field
+							column = "e." + field
 						} else {
-							field = field1
+							column = field1
 						}
 					}
-					if strings.ToLower(condition) == "in" {
-						sql += "(" + field + " " + condition + " (?)" + ") and"
-					} else if strings.ToLower(condition) == "not in" {
-						sql += "(" + field + " " + condition + " (?)" + ") and"
-					} else if strings.ToLower(condition) == "null" || strings.ToLower(condition) == "not null" {
-						sql += "(" + field + " is " + condition + ")" + " and"
-					} else {
-						sql += "(" + field + " " + condition + " ?" + ") and"
+					conditionSql, conditionValues, err := e.fieldConditionSql(field, column, condition, value)
+					if err != nil {
+						e.BuildError = err
+						return e
 					}
-					fieldValues = append(fieldValues, value)
+					sql += conditionSql + " and"
+					fieldValues = append(fieldValues, conditionValues...)
 				}
 				sql = strings.TrimSuffix(sql, "and") + ") or"
 			}
@@ -312,6 +400,7 @@ func (e *Collection) AddFieldToSelect(field string) CollectionInterface {
 }
 
 func (e *Collection) AddOrder(order string, dir string) CollectionInterface {
+	e.useField(order)
 	model, ok := e.Model.GetModel().(CollectionFieldInterface)
 	if ok {
 		field1 := model.AddJoinField(e, order)
@@ -325,6 +414,39 @@ func (e *Collection) AddOrder(order string, dir string) CollectionInterface {
 
 	return e
 }
+func (e *Collection) With(relations ...string) CollectionInterface {
+	e.Relations = append(e.Relations, relations...)
+	return e
+}
+
+func (e *Collection) AddRelationFilter(relation string, filters map[string]map[string]interface{}) CollectionInterface {
+	dbselect, err := relationSubSelect(e, relation, filters)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	sql, _ := dbselect.Assemble()
+	e.DbSelect.Where("exists (" + sql + ")")
+	return e
+}
+
+func (e *Collection) AddRelationCountFilter(relation string, filters map[string]map[string]interface{}, operator string, count int) CollectionInterface {
+	switch operator {
+	case "=", "!=", "<>", ">", ">=", "<", "<=":
+	default:
+		e.BuildError = fmt.Errorf("unknown count operator %s", operator)
+		return e
+	}
+	dbselect, err := relationSubSelect(e, relation, filters)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	sql, _ := dbselect.Columns("count(*)").Assemble()
+	e.DbSelect.Where("(" + sql + ") " + operator + " " + strconv.Itoa(count))
+	return e
+}
+
 func (e *Collection) Create() Basictablemodelinterface {
 	return e.Factory()
 }
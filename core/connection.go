@@ -18,6 +18,7 @@ type DBConnectionInterface interface {
 	Delete(table string, condition string) DBConnectionInterface
 	Update(table string, data map[string]interface{}, condition string) DBConnectionInterface
 	Expr(sql string, values ...interface{}) string
+	Exec(sql string, values ...interface{}) int64
 	GetDb() *gorm.DB
 	SetDb(db *gorm.DB) DBConnectionInterface
 }
@@ -273,6 +274,19 @@ func (this *DBConnection) Update(table string, data map[string]interface{}, cond
 	}
 	return this
 }
+
+// 執行原生 sql， 返回影響的行數
+func (this *DBConnection) Exec(sql string, values ...interface{}) int64 {
+	db := this.Db.Exec(sql, values...)
+	if db.Error != nil {
+		defer func() {
+			db.Error = nil
+		}()
+		panic(db.Error)
+	}
+	return db.RowsAffected
+}
+
 func (this *DBConnection) Expr(sql string, values ...interface{}) string {
 
 	return this.Db.Raw(sql, values...).ToSQL(func(tx *gorm.DB) *gorm.DB { return tx })
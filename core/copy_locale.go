@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	CopyLocaleSkipExisting = "skip_existing" // 目標 locale 已經有值的字段不複製
+	CopyLocaleOverwrite    = "overwrite"     // 覆蓋目標 locale 的值
+)
+
+// Mode 默認是 CopyLocaleSkipExisting， Filter 的格式跟 AddFieldToFilter 一樣
+// Fields 為空時複製所有非 IsGlobal 的 eav 字段， ChunkSize 是每個事務處理的實體數量， 默認 1000
+type CopyLocaleOptions struct {
+	Mode      string
+	Filter    map[string]map[string]interface{}
+	Fields    []string
+	ChunkSize int
+}
+
+// 複製 model 所有 value 表中 fromLocale 不為 null 的行到 toLocale， 例如新市場用 en-GB 的內容作為 en-AU 的初始值
+// 按主鍵分批， 每批一個事務， 返回複製的行數， 出錯時已經提交的批次不會回滾
+func CopyLocale(model Basictablemodelinterface, fromLocale string, toLocale string, options CopyLocaleOptions) (int64, error) {
+	var count int64
+	err := recoverError(func() {
+		// locale 可以包括 scope， 見 ScopedLocale
+		if fromLocale == "" || toLocale == "" || fromLocale == toLocale {
+			panic(fmt.Errorf("invalid locales %s => %s", fromLocale, toLocale))
+		}
+		if options.Mode == "" {
+			options.Mode = CopyLocaleSkipExisting
+		}
+		if options.Mode != CopyLocaleSkipExisting && options.Mode != CopyLocaleOverwrite {
+			panic(fmt.Errorf("unknown copy mode %s", options.Mode))
+		}
+		if options.ChunkSize <= 0 {
+			options.ChunkSize = 1000
+		}
+		// IsGlobal 的字段只保存在 default locale， 不複製
+		fields := make(map[string]Field)
+		for key, field := range model.GetEavFields() {
+			if !field.IsGlobal {
+				fields[key] = field
+			}
+		}
+		if len(options.Fields) > 0 {
+			selected := make(map[string]Field)
+			for _, name := range options.Fields {
+				name = strings.ToLower(name)
+				field, ok := fields[name]
+				if !ok {
+					panic(fmt.Errorf("%s is not a localizable eav field", name))
+				}
+				selected[name] = field
+			}
+			fields = selected
+		}
+		if len(fields) == 0 {
+			return
+		}
+
+		primaryField := model.GetPrimaryFieldName()
+		var lastId interface{}
+		for {
+			collection := newRelationCollection(model, selfFactory(model))
+			collection.WithoutDefaultScopes()
+			if len(options.Filter) > 0 {
+				collection.AddFieldToFilter(options.Filter)
+			}
+			if lastId != nil {
+				collection.AddFieldToFilter(map[string]map[string]interface{}{primaryField: {">": lastId}})
+			}
+			collection.AddOrder(primaryField, "asc").SetPageSize(options.ChunkSize)
+			elems := collection.GetElems()
+			if err := collection.GetLastError(); err != nil {
+				panic(err)
+			}
+			if len(elems) == 0 {
+				break
+			}
+			ids := make([]interface{}, 0, len(elems))
+			for _, elem := range elems {
+				ids = append(ids, elem.GetData(primaryField))
+			}
+			lastId = ids[len(ids)-1]
+			err := transaction(model.GetConnectionName(), func(connection DBConnectionInterface) {
+				count += copyLocaleRows(connection, model.GetTableName(), fields, ids, fromLocale, toLocale, options.Mode)
+			})
+			if err != nil {
+				panic(err)
+			}
+			if len(elems) < options.ChunkSize {
+				break
+			}
+		}
+		if count > 0 {
+			if err := InvalidateFlatIndex(model); err != nil {
+				panic(err)
+			}
+		}
+	})
+	return count, err
+}
+
+// 每個 value 表一條 insert ... select， 覆蓋時先刪除目標 locale 中來源有值的字段 (多值字段的行數可能不一樣)
+func copyLocaleRows(connection DBConnectionInterface, table string, fields map[string]Field, ids []interface{}, fromLocale string, toLocale string, mode string) int64 {
+	var count int64
+	from, fromScope := splitScopedLocale(fromLocale)
+	to, toScope := splitScopedLocale(toLocale)
+	for _, eavType := range eavFieldTypes(fields) {
+		names := make([]interface{}, 0)
+		for key, field := range fields {
+			if field.EavType == eavType {
+				names = append(names, key)
+			}
+		}
+		valueTable := table + "_" + eavType
+		condition := "s.locale = ? and s.scope = ? and s.entity_id in (?) and s.attribute_name in (?) and s.value is not null"
+		if mode == CopyLocaleOverwrite {
+			connection.Exec("delete t from "+valueTable+" as t inner join (select distinct s.entity_id, s.attribute_name from "+valueTable+" as s where "+condition+") as s"+
+				" on s.entity_id = t.entity_id and s.attribute_name = t.attribute_name where t.locale = ? and t.scope = ?", from, fromScope, ids, names, to, toScope)
+			count += connection.Exec("insert into "+valueTable+" (entity_id, locale, scope, attribute_name, position, value) select s.entity_id, ?, ?, s.attribute_name, s.position, s.value from "+valueTable+" as s where "+condition,
+				to, toScope, from, fromScope, ids, names)
+			continue
+		}
+		count += connection.Exec("insert into "+valueTable+" (entity_id, locale, scope, attribute_name, position, value) select s.entity_id, ?, ?, s.attribute_name, s.position, s.value from "+valueTable+" as s where "+condition+
+			" and not exists (select 1 from "+valueTable+" as t where t.entity_id = s.entity_id and t.attribute_name = s.attribute_name and t.locale = ? and t.scope = ?)",
+			to, toScope, from, fromScope, ids, names, to, toScope)
+	}
+	return count
+}
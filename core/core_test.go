@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -78,6 +81,217 @@ func GetUserTestFactory(locale string, defaultLocale string) Basictablemodelinte
 	return ModelFactory(callback)
 }
 
+func (e *UserTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"addresses": {Type: RelationHasMany, Factory: GetAddressTestFactory, ForeignKey: "user_id"},
+		"roles":     {Type: RelationManyToMany, Factory: GetRoleTestFactory, PivotTable: "user_role", PivotLocalKey: "user_id", PivotForeignKey: "role_id"},
+		"comments":  {Type: RelationMorphMany, Factory: GetCommentTestFactory, MorphType: "commentable_type", ForeignKey: "commentable_id"},
+	}
+}
+
+type AddressTest struct {
+	EntityId uint64
+	UserId   uint64
+	Postcode string
+	City     string
+}
+
+func (e *AddressTest) GetTableName() string {
+	return "address"
+}
+
+func (e *AddressTest) GetTableFields() map[string]Field {
+	return map[string]Field{
+		"entity_id": {Name: "EntityId", IsEav: false, DbType: "uint64"},
+		"user_id":   {Name: "UserId", IsEav: false, DbType: "uint64"},
+		"postcode":  {Name: "Postcode", IsEav: false, DbType: "string"},
+		"city":      {Name: "City", IsEav: true, DbType: "string", EavType: "varchar"},
+	}
+}
+func (e *AddressTest) GetPrimaryFieldName() string {
+	return "entity_id"
+}
+func (e *AddressTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"user": {Type: RelationBelongsTo, Factory: GetUserTestFactory, ForeignKey: "user_id"},
+	}
+}
+
+func GetAddressTestFactory(locale string, defaultLocale string) Basictablemodelinterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &AddressTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return ModelFactory(callback)
+}
+
+type RoleTest struct {
+	EntityId uint64
+	Code     string
+	Label    string
+}
+
+func (e *RoleTest) GetTableName() string {
+	return "role"
+}
+
+func (e *RoleTest) GetTableFields() map[string]Field {
+	return map[string]Field{
+		"entity_id": {Name: "EntityId", IsEav: false, DbType: "uint64"},
+		"code":      {Name: "Code", IsEav: false, DbType: "string"},
+		"label":     {Name: "Label", IsEav: true, DbType: "string", EavType: "varchar"},
+	}
+}
+func (e *RoleTest) GetPrimaryFieldName() string {
+	return "entity_id"
+}
+
+func GetRoleTestFactory(locale string, defaultLocale string) Basictablemodelinterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &RoleTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return ModelFactory(callback)
+}
+
+type CommentTest struct {
+	EntityId        uint64
+	CommentableType string
+	CommentableId   uint64
+	Body            string
+}
+
+func (e *CommentTest) GetTableName() string {
+	return "comment"
+}
+
+func (e *CommentTest) GetTableFields() map[string]Field {
+	return map[string]Field{
+		"entity_id":        {Name: "EntityId", IsEav: false, DbType: "uint64"},
+		"commentable_type": {Name: "CommentableType", IsEav: false, DbType: "string"},
+		"commentable_id":   {Name: "CommentableId", IsEav: false, DbType: "uint64"},
+		"body":             {Name: "Body", IsEav: false, DbType: "string"},
+	}
+}
+func (e *CommentTest) GetPrimaryFieldName() string {
+	return "entity_id"
+}
+func (e *CommentTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"commentable": {Type: RelationMorphTo, MorphType: "commentable_type", ForeignKey: "commentable_id"},
+	}
+}
+
+func GetCommentTestFactory(locale string, defaultLocale string) Basictablemodelinterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &CommentTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return ModelFactory(callback)
+}
+
+type CategoryTest struct {
+	EntityId uint64
+	ParentId uint64
+	Code     string
+	Name     string
+	Path     string
+	Level    uint32
+	Position uint32
+}
+
+func (e *CategoryTest) GetTableName() string {
+	return "category"
+}
+
+func (e *CategoryTest) GetTableFields() map[string]Field {
+	return map[string]Field{
+		"entity_id": {Name: "EntityId", IsEav: false, DbType: "uint64"},
+		"parent_id": {Name: "ParentId", IsEav: false, DbType: "uint64"},
+		"code":      {Name: "Code", IsEav: false, DbType: "string"},
+		"name":      {Name: "Name", IsEav: true, DbType: "string", EavType: "varchar"},
+		"path":      {Name: "Path", IsEav: false, DbType: "string"},
+		"level":     {Name: "Level", IsEav: false, DbType: "uint32"},
+		"position":  {Name: "Position", IsEav: false, DbType: "uint32"},
+	}
+}
+func (e *CategoryTest) GetPrimaryFieldName() string {
+	return "entity_id"
+}
+func (e *CategoryTest) IsTree() bool {
+	return true
+}
+func (e *CategoryTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"parent":   {Type: RelationBelongsTo, ForeignKey: "parent_id", Self: true},
+		"children": {Type: RelationHasMany, ForeignKey: "parent_id", Self: true},
+		"comments": {Type: RelationMorphMany, Factory: GetCommentTestFactory, MorphType: "commentable_type", ForeignKey: "commentable_id"},
+	}
+}
+
+func GetCategoryTestFactory(locale string, defaultLocale string) Basictablemodelinterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &CategoryTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return ModelFactory(callback)
+}
+
+func ConvertModelToCategoryTest(tableModel Basictablemodelinterface) *CategoryTest {
+	if m, ok := tableModel.GetModel().(*CategoryTest); ok {
+		return m
+	}
+	return &CategoryTest{}
+}
+
+func GetCategoryTestCollectionFactory(locale string, defaultLocale string) CollectionInterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &CategoryTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return CollectionFactory(callback)
+}
+
+type ProductTest struct {
+	EntityId       uint64
+	Sku            string
+	Name           string
+	AttributeSetId uint64
+	Tags           []string
+}
+
+func (e *ProductTest) GetTableName() string {
+	return "product"
+}
+
+func (e *ProductTest) GetTableFields() map[string]Field {
+	return map[string]Field{
+		"entity_id":        {Name: "EntityId", IsEav: false, DbType: "uint64"},
+		"sku":              {Name: "Sku", IsEav: false, DbType: "string"},
+		"name":             {Name: "Name", IsEav: true, DbType: "string", EavType: "varchar"},
+		"attribute_set_id": {Name: "AttributeSetId", IsEav: false, DbType: "uint64"},
+		"tags":             {Name: "Tags", IsEav: true, EavType: "varchar", IsMultiValue: true},
+	}
+}
+func (e *ProductTest) GetPrimaryFieldName() string {
+	return "entity_id"
+}
+func (e *ProductTest) IsRuntimeEav() bool {
+	return true
+}
+func (e *ProductTest) IsAttributeSetEav() bool {
+	return true
+}
+
+func GetProductTestFactory(locale string, defaultLocale string) Basictablemodelinterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &ProductTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return ModelFactory(callback)
+}
+
+func GetProductTestCollectionFactory(locale string, defaultLocale string) CollectionInterface {
+	callback := func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &ProductTest{}, Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+	}
+	return CollectionFactory(callback)
+}
+
 const testDBName = "testdb"
 
 var testDB *gorm.DB
@@ -121,6 +335,9 @@ func TestMain(m *testing.M) {
 	testConnectionName = "test"
 	InitDB(dsn, testConnectionName)
 	defer CloseDb(testConnectionName)
+	RegisterModel("user", &UserTest{}, GetUserTestFactory)
+	RegisterModel("category", &CategoryTest{}, GetCategoryTestFactory)
+	RegisterModel("comment", &CommentTest{}, GetCommentTestFactory)
 
 	// 运行测试
 	exitCode := m.Run()
@@ -406,3 +623,1459 @@ func TestTransaction(t *testing.T) {
 	dbUserModels3 := userModelCollection3.GetElems()
 	assert.Len(dbUserModels3, 0)
 }
+
+func TestLoadByFields(t *testing.T) {
+	assert := assert.New(t)
+
+	userModel := GetUserTestFactory("en-US", "en-US")
+	userModel.SetData("name", "Load Fields").SetData("age", 31).Save()
+	user := ConvertModelToUserTest(userModel)
+	userModel2 := GetUserTestFactory("zh-CN", "en-US").LoadById(user.EntityId)
+	userModel2.SetData("name", "中文 load fields").Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Load Fields").SetData("age", 32).Save()
+
+	loadModel := GetUserTestFactory("en-US", "en-US")
+	loadModel.LoadByFields(map[string]interface{}{"name": "Load Fields", "age": 31})
+	assert.Nil(loadModel.GetLastError())
+	assert.Equal(user.EntityId, ConvertModelToUserTest(loadModel).EntityId)
+
+	// eav 字段用 model 的 locale
+	loadModel = GetUserTestFactory("zh-CN", "en-US")
+	loadModel.LoadByFields(map[string]interface{}{"name": "中文 load fields"})
+	assert.Nil(loadModel.GetLastError())
+	assert.Equal(user.EntityId, ConvertModelToUserTest(loadModel).EntityId)
+
+	loadModel = GetUserTestFactory("en-US", "en-US")
+	loadModel.LoadOneByFilter(map[string]map[string]interface{}{"name": {"=": "Load Fields"}, "age": {">": 31}})
+	assert.Nil(loadModel.GetLastError())
+	assert.True(ConvertToInt32(loadModel.GetData("age")) == 32)
+
+	loadModel = GetUserTestFactory("en-US", "en-US")
+	loadModel.LoadByFields(map[string]interface{}{"name": "Load Fields"})
+	assert.ErrorIs(loadModel.GetLastError(), ErrMultipleResults)
+
+	loadModel = GetUserTestFactory("en-US", "en-US")
+	loadModel.LoadByFields(map[string]interface{}{"name": "not exists"})
+	assert.ErrorIs(loadModel.GetLastError(), ErrNotFound)
+}
+
+func TestRelations(t *testing.T) {
+	assert := assert.New(t)
+	userModelCollection := GetUserTestCollectionFactory("en-US", "en-US")
+	for _, user := range userModelCollection.GetElems() {
+		user.Delete()
+	}
+
+	admin := GetRoleTestFactory("en-US", "en-US")
+	admin.SetData("code", "admin").SetData("label", "Administrator").Save()
+	GetRoleTestFactory("zh-CN", "en-US").LoadById(admin.GetData("entity_id")).SetData("label", "管理員").Save()
+	editor := GetRoleTestFactory("en-US", "en-US")
+	editor.SetData("code", "editor").SetData("label", "Editor").Save()
+
+	userIds := make([]interface{}, 0)
+	for index, name := range []string{"Relation1", "Relation2", "Relation3"} {
+		userModel := GetUserTestFactory("en-US", "en-US")
+		userModel.SetData("name", name).SetData("age", 20+index).Save()
+		userId := userModel.GetData("entity_id")
+		userIds = append(userIds, userId)
+		for i := 0; i < index; i++ {
+			address := GetAddressTestFactory("en-US", "en-US")
+			address.SetData("user_id", userId).SetData("postcode", fmt.Sprintf("0%d", i)).SetData("city", fmt.Sprintf("City %d", i)).Save()
+		}
+		db := GetConnection(testConnectionName)
+		db.Exec("insert into user_role (user_id, role_id) values (?, ?)", userId, admin.GetData("entity_id"))
+		if index == 2 {
+			db.Exec("insert into user_role (user_id, role_id) values (?, ?)", userId, editor.GetData("entity_id"))
+		}
+	}
+
+	collection := GetUserTestCollectionFactory("zh-CN", "en-US").With("addresses.user", "roles")
+	users := collection.GetElems()
+	assert.Nil(collection.GetLastError())
+	assert.Len(users, 3)
+	for index, user := range users {
+		addresses := user.GetRelated("addresses")
+		assert.Len(addresses, index)
+		for _, address := range addresses {
+			assert.Equal(user.GetData("entity_id"), address.GetData("user_id"))
+			assert.Equal("zh-CN", address.GetLocale())
+			assert.Len(address.GetRelated("user"), 1)
+		}
+		roles := user.GetRelated("roles")
+		if index == 2 {
+			assert.Len(roles, 2)
+		} else {
+			assert.Len(roles, 1)
+		}
+		assert.Equal("管理員", roles[0].GetData("label"))
+	}
+
+	address := GetAddressTestFactory("en-US", "en-US")
+	address.LoadByFields(map[string]interface{}{"user_id": userIds[1]}).LoadRelation("user")
+	assert.Nil(address.GetLastError())
+	assert.Len(address.GetRelated("user"), 1)
+	assert.Equal("Relation2", address.GetRelated("user")[0].GetData("name"))
+
+	collection = GetUserTestCollectionFactory("en-US", "en-US").With("unknown")
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
+
+// 關聯 model 的表不存在， 用來測試關聯加載的錯誤
+type MissingTableTest struct {
+	AddressTest
+}
+
+func (e *MissingTableTest) GetTableName() string {
+	return "missing_table"
+}
+
+type BrokenRelationUserTest struct {
+	UserTest
+}
+
+func (e *BrokenRelationUserTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"missing": {Type: RelationHasMany, Factory: testModelFactory(func() BasicModelInterface { return &MissingTableTest{} }), ForeignKey: "user_id"},
+	}
+}
+
+func TestRelationLoadError(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Broken relation").Save()
+	assert.Nil(user.GetLastError())
+
+	// 關聯加載失敗時返回錯誤， 不是空的關聯
+	collection := testCollectionFactory(func() BasicModelInterface { return &BrokenRelationUserTest{} })("en-US", "en-US").With("missing")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": user.GetData("entity_id")}})
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
+
+func TestRelationFilter(t *testing.T) {
+	assert := assert.New(t)
+	userModelCollection := GetUserTestCollectionFactory("en-US", "en-US")
+	for _, user := range userModelCollection.GetElems() {
+		user.Delete()
+	}
+
+	for index, name := range []string{"Filter1", "Filter2", "Filter3"} {
+		userModel := GetUserTestFactory("en-US", "en-US")
+		userModel.SetData("name", name).SetData("age", 20+index).Save()
+		for i := 0; i <= index; i++ {
+			address := GetAddressTestFactory("en-US", "en-US")
+			address.SetData("user_id", userModel.GetData("entity_id")).SetData("city", fmt.Sprintf("City %d", i)).Save()
+			GetAddressTestFactory("zh-CN", "en-US").LoadById(address.GetData("entity_id")).SetData("city", fmt.Sprintf("城市 %d", i)).Save()
+		}
+	}
+
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddRelationFilter("addresses", map[string]map[string]interface{}{"city": {"=": "City 1"}})
+	assert.Len(collection.GetElems(), 2)
+	assert.Nil(collection.GetLastError())
+
+	// 關聯的 eav 字段用 collection 的 locale
+	collection = GetUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddRelationFilter("addresses", map[string]map[string]interface{}{"city": {"=": "城市 2"}})
+	assert.Len(collection.GetElems(), 1)
+
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddRelationCountFilter("addresses", nil, ">=", 2)
+	assert.Len(collection.GetElems(), 2)
+	assert.Equal(2, collection.GetSize())
+
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddRelationFilter("unknown", nil)
+	assert.Len(collection.GetElems(), 0)
+	assert.NotNil(collection.GetLastError())
+}
+
+func TestPolymorphicRelations(t *testing.T) {
+	assert := assert.New(t)
+
+	root := GetCategoryTestFactory("en-US", "en-US")
+	root.SetData("code", "root").SetData("name", "Root").Save()
+	child := GetCategoryTestFactory("en-US", "en-US")
+	child.SetData("code", "child").SetData("parent_id", root.GetData("entity_id")).SetData("name", "Child").Save()
+	GetCategoryTestFactory("zh-CN", "en-US").LoadById(child.GetData("entity_id")).SetData("name", "子分類").Save()
+	userModel := GetUserTestFactory("en-US", "en-US")
+	userModel.SetData("name", "Commenter").SetData("age", 30).Save()
+
+	GetCommentTestFactory("en-US", "en-US").SetData("commentable_type", "category").SetData("commentable_id", child.GetData("entity_id")).SetData("body", "on category").Save()
+	GetCommentTestFactory("en-US", "en-US").SetData("commentable_type", "user").SetData("commentable_id", userModel.GetData("entity_id")).SetData("body", "on user").Save()
+
+	// self-referencing
+	categories := GetCategoryTestCollectionFactory("zh-CN", "en-US").With("parent", "children", "comments")
+	categories.AddFieldToFilter(map[string]map[string]interface{}{"code": {"in": []string{"root", "child"}}})
+	assert.Len(categories.GetElems(), 2)
+	for _, category := range categories.GetElems() {
+		if category.GetData("code") == "root" {
+			assert.Len(category.GetRelated("parent"), 0)
+			assert.Len(category.GetRelated("children"), 1)
+			assert.Equal("子分類", category.GetRelated("children")[0].GetData("name"))
+			assert.Len(category.GetRelated("comments"), 0)
+		} else {
+			assert.Len(category.GetRelated("parent"), 1)
+			assert.Equal("Root", category.GetRelated("parent")[0].GetData("name"))
+			assert.Len(category.GetRelated("comments"), 1)
+		}
+	}
+
+	// morph_to 的 entity type 從 registry 解析
+	comments := CollectionFactory(func() Basictablemodelinterface {
+		return &Basictablemodel{Model: &CommentTest{}, Connection: testConnectionName, Locale: "en-US", DefaultLocale: "en-US"}
+	}).With("commentable")
+	for _, comment := range comments.GetElems() {
+		commentable := comment.GetRelated("commentable")
+		assert.Len(commentable, 1)
+		assert.Equal(comment.GetData("commentable_type"), GetEntityType(commentable[0]))
+	}
+	assert.Nil(comments.GetLastError())
+
+	userModel.LoadRelation("comments")
+	assert.Len(userModel.GetRelated("comments"), 1)
+	assert.Equal("on user", userModel.GetRelated("comments")[0].GetData("body"))
+
+	users := GetUserTestCollectionFactory("en-US", "en-US")
+	users.AddRelationFilter("comments", map[string]map[string]interface{}{"body": {"=": "on user"}})
+	assert.Len(users.GetElems(), 1)
+}
+
+func TestTree(t *testing.T) {
+	assert := assert.New(t)
+
+	newCategory := func(code string, parent Basictablemodelinterface) Basictablemodelinterface {
+		category := GetCategoryTestFactory("en-US", "en-US")
+		category.SetData("code", code).SetData("name", code)
+		if parent != nil {
+			category.SetData("parent_id", parent.GetData("entity_id"))
+		}
+		category.Save()
+		assert.Nil(category.GetLastError())
+		return category
+	}
+	a := newCategory("tree-a", nil)
+	b := newCategory("tree-b", a)
+	c := newCategory("tree-c", a)
+	d := newCategory("tree-d", b)
+
+	treeA := ConvertModelToCategoryTest(a)
+	treeB := ConvertModelToCategoryTest(b)
+	treeC := ConvertModelToCategoryTest(c)
+	treeD := ConvertModelToCategoryTest(d)
+	assert.Equal(uint32(0), treeA.Level)
+	assert.Equal(fmt.Sprintf("%d/%d/%d", treeA.EntityId, treeB.EntityId, treeD.EntityId), treeD.Path)
+	assert.Equal(uint32(2), treeD.Level)
+	assert.Equal(uint32(1), treeB.Position)
+	assert.Equal(uint32(2), treeC.Position)
+
+	assert.Len(a.GetChildren().GetElems(), 2)
+	assert.Len(a.GetDescendants().GetElems(), 3)
+	ancestors := d.GetAncestors().GetElems()
+	assert.Len(ancestors, 2)
+	assert.Equal("tree-a", ancestors[0].GetData("code"))
+
+	// d 移到 a 的第一個位置
+	d.MoveTo(a, 1)
+	assert.Nil(d.GetLastError())
+	children := a.GetChildren().GetElems()
+	assert.Len(children, 3)
+	assert.Equal("tree-d", children[0].GetData("code"))
+	assert.Equal("tree-b", children[1].GetData("code"))
+	assert.Equal("tree-c", children[2].GetData("code"))
+	assert.Len(b.GetChildren().GetElems(), 0)
+
+	// b 移到 c 下面， b 的子孫節點 path 一起更新
+	newCategory("tree-e", b)
+	b.MoveTo(c, 0)
+	assert.Nil(b.GetLastError())
+	e := GetCategoryTestFactory("en-US", "en-US").LoadByFields(map[string]interface{}{"code": "tree-e"})
+	assert.Equal(fmt.Sprintf("%d/%d/%d/%d", treeA.EntityId, treeC.EntityId, treeB.EntityId, ConvertModelToCategoryTest(e).EntityId), ConvertModelToCategoryTest(e).Path)
+	assert.Equal(uint32(3), ConvertModelToCategoryTest(e).Level)
+	assert.Len(GetCategoryTestFactory("en-US", "en-US").LoadById(treeA.EntityId).GetChildren().GetElems(), 2)
+
+	// 不能移到自己的子樹
+	c.MoveTo(b, 0)
+	assert.ErrorIs(c.GetLastError(), ErrTreeCycle)
+
+	subtree := GetCategoryTestCollectionFactory("en-US", "en-US").AddSubtreeFilter(treeC.EntityId, true)
+	assert.Len(subtree.GetElems(), 3)
+
+	// 直接 Save 修改 parent_id 和 position 時， 兄弟節點同樣補位和騰位
+	d = GetCategoryTestFactory("en-US", "en-US").LoadById(treeD.EntityId)
+	d.SetData("parent_id", treeC.EntityId).Save()
+	assert.Nil(d.GetLastError())
+	children = GetCategoryTestFactory("en-US", "en-US").LoadById(treeA.EntityId).GetChildren().GetElems()
+	assert.Len(children, 1)
+	assert.Equal(uint32(1), ConvertModelToCategoryTest(children[0]).Position)
+	children = GetCategoryTestFactory("en-US", "en-US").LoadById(treeC.EntityId).GetChildren().GetElems()
+	assert.Len(children, 2)
+	assert.Equal("tree-b", children[0].GetData("code"))
+	assert.Equal("tree-d", children[1].GetData("code"))
+	assert.Equal(uint32(2), ConvertModelToCategoryTest(children[1]).Position)
+
+	d.SetData("position", 1).Save()
+	assert.Nil(d.GetLastError())
+	children = GetCategoryTestFactory("en-US", "en-US").LoadById(treeC.EntityId).GetChildren().GetElems()
+	assert.Equal("tree-d", children[0].GetData("code"))
+	assert.Equal("tree-b", children[1].GetData("code"))
+	assert.Equal(uint32(1), ConvertModelToCategoryTest(children[0]).Position)
+	assert.Equal(uint32(2), ConvertModelToCategoryTest(children[1]).Position)
+
+	// 刪除節點時子孫節點一起刪除
+	GetCategoryTestFactory("en-US", "en-US").LoadById(treeC.EntityId).Delete()
+	assert.Len(GetCategoryTestFactory("en-US", "en-US").LoadById(treeA.EntityId).GetDescendants().GetElems(), 0)
+}
+
+func TestRuntimeEavAttribute(t *testing.T) {
+	assert := assert.New(t)
+
+	subtitle := &EavAttribute{EntityType: "product", Code: "subtitle", BackendType: "varchar", Labels: map[string]string{"en-US": "Subtitle", "zh-CN": "副標題"}}
+	assert.Nil(SaveEavAttribute(testConnectionName, subtitle))
+	assert.NotEqual(uint64(0), subtitle.AttributeId)
+	assert.Error(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "bad", BackendType: "unknown"}))
+
+	attribute, ok, err := GetEavAttribute(testConnectionName, "product", "subtitle")
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal("副標題", attribute.GetLabel("zh-HK", "zh-CN"))
+	assert.Equal("Subtitle", attribute.GetLabel("de-DE", "en-US"))
+
+	product := GetProductTestFactory("en-US", "en-US")
+	_, ok = product.GetEavFields()["subtitle"]
+	assert.True(ok)
+	product.SetData("sku", "runtime-1").SetData("name", "Runtime").SetData("subtitle", "English subtitle").Save()
+	assert.Nil(product.GetLastError())
+	GetProductTestFactory("zh-CN", "en-US").LoadById(product.GetData("entity_id")).SetData("subtitle", "中文副標題").Save()
+
+	product2 := GetProductTestFactory("zh-CN", "en-US").LoadById(product.GetData("entity_id"))
+	assert.Equal("中文副標題", product2.GetData("subtitle"))
+	product3 := GetProductTestFactory("de-DE", "en-US").LoadById(product.GetData("entity_id"))
+	assert.Equal("English subtitle", product3.GetData("subtitle"))
+
+	collection := GetProductTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"subtitle": {"=": "中文副標題"}})
+	assert.Len(collection.GetElems(), 1)
+
+	// required
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "brand", BackendType: "varchar", IsRequired: true}))
+	product4 := GetProductTestFactory("en-US", "en-US")
+	product4.SetData("sku", "runtime-2").Save()
+	assert.EqualError(product4.GetLastError(), "brand is required")
+	assert.Nil(DeleteEavAttribute(testConnectionName, "product", "brand"))
+	product4.Save()
+	assert.Nil(product4.GetLastError())
+
+	// 事務中讀取的字段不緩存， 回滾後不可見
+	err = transaction(testConnectionName, func(connection DBConnectionInterface) {
+		connection.Insert("eav_attribute", map[string]interface{}{"entity_type": "product", "attribute_code": "rollback_only", "backend_type": "varchar"})
+		inTx := GetProductTestFactory("en-US", "en-US")
+		inTx.GetConnection().SetDb(connection.GetDb())
+		clearEavAttributeCache(testConnectionName, "product")
+		assert.Contains(inTx.GetEavFields(), "rollback_only")
+		panic(fmt.Errorf("rollback"))
+	})
+	assert.Error(err)
+	_, ok, err = GetEavAttribute(testConnectionName, "product", "rollback_only")
+	assert.Nil(err)
+	assert.False(ok)
+	assert.NotContains(GetProductTestFactory("en-US", "en-US").GetEavFields(), "rollback_only")
+}
+
+func TestAttributeSet(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "warranty", BackendType: "varchar"}))
+	electronics := &AttributeSet{EntityType: "product", Name: "Electronics", Groups: []AttributeGroup{
+		{Name: "General", SortOrder: 1, Attributes: []string{"name"}},
+		{Name: "Details", SortOrder: 2, Attributes: []string{"warranty"}},
+	}}
+	assert.Nil(SaveAttributeSet(testConnectionName, electronics))
+	books := &AttributeSet{EntityType: "product", Name: "Books", Groups: []AttributeGroup{
+		{Name: "General", Attributes: []string{"name"}},
+	}}
+	assert.Nil(SaveAttributeSet(testConnectionName, books))
+
+	set, err := GetAttributeSet(testConnectionName, electronics.AttributeSetId)
+	assert.Nil(err)
+	assert.Len(set.Groups, 2)
+	assert.Equal("Details", set.Groups[1].Name)
+	assert.Equal([]string{"warranty"}, set.Groups[1].Attributes)
+
+	tv := GetProductTestFactory("en-US", "en-US")
+	tv.SetData("sku", "set-tv").SetData("attribute_set_id", electronics.AttributeSetId).SetData("name", "TV").SetData("warranty", "2 years").Save()
+	assert.Nil(tv.GetLastError())
+	book := GetProductTestFactory("en-US", "en-US")
+	book.SetData("sku", "set-book").SetData("attribute_set_id", books.AttributeSetId).SetData("name", "Book").SetData("warranty", "none").Save()
+	assert.Nil(book.GetLastError())
+	assert.Len(book.GetEavFields(), 1)
+	set, err = book.GetAttributeSet()
+	assert.Nil(err)
+	assert.Equal("Books", set.Name)
+
+	// attribute set 不存在時返回錯誤， GetEavFields 返回所有字段並設置 LastError
+	missing := GetProductTestFactory("en-US", "en-US").SetData("attribute_set_id", 999999)
+	_, err = missing.GetAttributeSet()
+	assert.ErrorIs(err, ErrNotFound)
+	assert.Contains(missing.GetEavFields(), "warranty")
+	assert.ErrorIs(missing.GetLastError(), ErrNotFound)
+
+	tv2 := GetProductTestFactory("en-US", "en-US").LoadById(tv.GetData("entity_id"))
+	assert.Equal("2 years", tv2.GetData("warranty"))
+	assert.Equal("TV", tv2.GetData("name"))
+	book2 := GetProductTestFactory("en-US", "en-US").LoadById(book.GetData("entity_id"))
+	assert.Equal("Book", book2.GetData("name"))
+	assert.Nil(book2.GetData("warranty"))
+	assert.NotContains(book2.GetResourceModel().GetEavAsTable(), "e_warranty")
+	book3 := GetProductTestFactory("en-US", "en-US").LoadByFields(map[string]interface{}{"sku": "set-book"})
+	assert.Nil(book3.GetLastError())
+	assert.Equal("Book", book3.GetData("name"))
+	assert.NotContains(book3.GetResourceModel().GetEavAsTable(), "e_warranty")
+	tv3 := GetProductTestFactory("en-US", "en-US").LoadOneByFilter(map[string]map[string]interface{}{"sku": {"=": "set-tv"}})
+	assert.Nil(tv3.GetLastError())
+	assert.Equal("2 years", tv3.GetData("warranty"))
+
+	collection := GetProductTestCollectionFactory("en-US", "en-US").SetAttributeSet(books.AttributeSetId)
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("set-book", collection.GetElems()[0].GetData("sku"))
+}
+
+func TestAttributeOption(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "color", BackendType: "varchar", Input: InputSelect}))
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "size", BackendType: "varchar", Input: InputMultiselect}))
+	red := &AttributeOption{EntityType: "product", AttributeCode: "color", SortOrder: 1, Labels: map[string]string{"en-US": "Red", "zh-CN": "紅色"}}
+	blue := &AttributeOption{EntityType: "product", AttributeCode: "color", SortOrder: 2, Labels: map[string]string{"en-US": "Blue"}}
+	small := &AttributeOption{EntityType: "product", AttributeCode: "size", SortOrder: 1, Labels: map[string]string{"en-US": "Small"}}
+	large := &AttributeOption{EntityType: "product", AttributeCode: "size", SortOrder: 2, Labels: map[string]string{"en-US": "Large"}}
+	for _, option := range []*AttributeOption{red, blue, small, large} {
+		assert.Nil(SaveAttributeOption(testConnectionName, option))
+	}
+	options, err := GetAttributeOptions(testConnectionName, "product", "color")
+	assert.Nil(err)
+	assert.Len(options, 2)
+	assert.Equal("紅色", options[0].GetLabel("zh-CN", "en-US"))
+
+	shirt := GetProductTestFactory("en-US", "en-US")
+	shirt.SetData("sku", "option-shirt").SetData("color", red.OptionId).SetData("size", fmt.Sprintf("%d,%d", small.OptionId, large.OptionId)).Save()
+	assert.Nil(shirt.GetLastError())
+	hat := GetProductTestFactory("en-US", "en-US")
+	hat.SetData("sku", "option-hat").SetData("color", blue.OptionId).SetData("size", small.OptionId).Save()
+	assert.Nil(hat.GetLastError())
+
+	shirt2 := GetProductTestFactory("zh-CN", "en-US").LoadById(shirt.GetData("entity_id"))
+	assert.Equal("紅色", shirt2.GetDataLabel("color"))
+	assert.Equal("Small, Large", shirt2.GetDataLabel("size"))
+	assert.Equal("option-shirt", shirt2.GetDataLabel("sku"))
+	// 選項加載失敗時不 panic， 返回原值並設置 LastError
+	connection := newConnection(testConnectionName)
+	connection.Exec("rename table eav_attribute_option_value to eav_attribute_option_value_tmp")
+	clearAttributeOptionCache(testConnectionName, "product", "color")
+	assert.Equal(ConvertToString(red.OptionId), shirt2.GetDataLabel("color"))
+	assert.Error(shirt2.GetLastError())
+	connection.Exec("rename table eav_attribute_option_value_tmp to eav_attribute_option_value")
+
+	collection := GetProductTestCollectionFactory("zh-CN", "en-US").AddOptionLabelFilter("color", map[string]interface{}{"=": "紅色"})
+	assert.Len(collection.GetElems(), 1)
+	collection = GetProductTestCollectionFactory("en-US", "en-US").AddOptionLabelFilter("size", map[string]interface{}{"=": "Large"})
+	assert.Len(collection.GetElems(), 1)
+	collection = GetProductTestCollectionFactory("en-US", "en-US").AddOptionLabelFilter("color", map[string]interface{}{"in": []string{"Red", "Blue"}}).AddOptionLabelOrder("color", "asc")
+	assert.Len(collection.GetElems(), 2)
+	assert.Equal("option-hat", collection.GetElems()[0].GetData("sku"))
+
+	collection = GetProductTestCollectionFactory("en-US", "en-US").AddOptionLabelOrder("size", "asc")
+	collection.Load()
+	assert.Error(collection.GetLastError())
+	collection = GetProductTestCollectionFactory("en-US", "en-US").AddOptionLabelFilter("sku", map[string]interface{}{"=": "x"})
+	collection.Load()
+	assert.Error(collection.GetLastError())
+
+	assert.Nil(DeleteAttributeOption(testConnectionName, "product", "color", blue.OptionId))
+	assert.Equal("", GetProductTestFactory("en-US", "en-US").LoadById(hat.GetData("entity_id")).GetDataLabel("color"))
+}
+
+func TestEavBackendTypes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "priority", BackendType: "int", IsGlobal: true}))
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "price", BackendType: "decimal", IsGlobal: true}))
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "release_at", BackendType: "datetime", IsGlobal: true}))
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "description", BackendType: "text"}))
+
+	description := strings.Repeat("long description ", 50)
+	first := GetProductTestFactory("en-US", "en-US")
+	first.SetData("sku", "backend-9").SetData("priority", "9").SetData("price", 5.5).SetData("release_at", "2024-01-02 03:04:05").SetData("description", description).Save()
+	assert.Nil(first.GetLastError())
+	second := GetProductTestFactory("en-US", "en-US")
+	second.SetData("sku", "backend-10").SetData("priority", 10).SetData("price", "12.25").Save()
+	assert.Nil(second.GetLastError())
+
+	first2 := GetProductTestFactory("en-US", "en-US").LoadById(first.GetData("entity_id"))
+	assert.Equal(int64(9), first2.GetData("priority"))
+	assert.Equal(5.5, first2.GetData("price"))
+	assert.Equal("2024-01-02 03:04:05", first2.GetData("release_at"))
+	assert.Equal(description, first2.GetData("description"))
+
+	collection := GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"sku": {"like": "backend-%"}}).AddOrder("priority", "asc")
+	assert.Len(collection.GetElems(), 2)
+	assert.Equal("backend-9", collection.GetElems()[0].GetData("sku"))
+
+	collection = GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"price": {">": "6"}})
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("backend-10", collection.GetElems()[0].GetData("sku"))
+
+	// like 等模式的值不轉換為 backend 的類型
+	collection = GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"sku": {"like": "backend-%"}})
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"priority": {"like": "1%"}})
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("backend-10", collection.GetElems()[0].GetData("sku"))
+	collection = GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"release_at": {"starts": "2024-01"}})
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("backend-9", collection.GetElems()[0].GetData("sku"))
+	collection = GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"priority": {"in": []string{"9", "10"}}})
+	assert.Len(collection.GetElems(), 2)
+
+	_, err := GetEavValueTableSql("product", "entity_id", "unknown")
+	assert.Error(err)
+}
+
+func TestMultiValueEav(t *testing.T) {
+	assert := assert.New(t)
+
+	shirt := GetProductTestFactory("en-US", "en-US")
+	shirt.SetData("sku", "multi-shirt").SetData("tags", []string{"summer", "cotton", "sale"}).Save()
+	assert.Nil(shirt.GetLastError())
+	assert.Equal([]string{"summer", "cotton", "sale"}, shirt.GetModel().(*ProductTest).Tags)
+	hat := GetProductTestFactory("en-US", "en-US")
+	hat.SetData("sku", "multi-hat").SetData("tags", []interface{}{"summer", "straw"}).Save()
+	assert.Nil(hat.GetLastError())
+	GetProductTestFactory("zh-CN", "en-US").LoadById(hat.GetData("entity_id")).SetData("tags", []string{"夏天"}).Save()
+
+	shirt2 := GetProductTestFactory("zh-CN", "en-US").LoadById(shirt.GetData("entity_id"))
+	assert.Equal([]string{"summer", "cotton", "sale"}, shirt2.GetData("tags"))
+	hat2 := GetProductTestFactory("zh-CN", "en-US").LoadById(hat.GetData("entity_id"))
+	assert.Equal([]string{"夏天"}, hat2.GetData("tags"))
+
+	shirt2.SetData("tags", []string{"sale"}).Save()
+	assert.Nil(shirt2.GetLastError())
+	assert.Equal([]string{"sale"}, GetProductTestFactory("zh-CN", "en-US").LoadById(shirt.GetData("entity_id")).GetData("tags"))
+	assert.Equal([]string{"summer", "cotton", "sale"}, GetProductTestFactory("en-US", "en-US").LoadById(shirt.GetData("entity_id")).GetData("tags"))
+
+	collection := GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"tags": {"contains any": []string{"straw", "cotton"}}})
+	assert.Len(collection.GetElems(), 2)
+	collection = GetProductTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"tags": {"contains all": []string{"summer", "sale"}}})
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("multi-shirt", collection.GetElems()[0].GetData("sku"))
+	collection = GetProductTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"tags": {"contains any": []string{"夏天"}}})
+	assert.Len(collection.GetElems(), 1)
+
+	// 長列表超過默認的 group_concat_max_len (1024) 也不截斷
+	tags := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		tags = append(tags, fmt.Sprintf("long-tag-%03d", i))
+	}
+	long := GetProductTestFactory("en-US", "en-US")
+	long.SetData("sku", "multi-long").SetData("tags", tags).Save()
+	assert.Nil(long.GetLastError())
+	assert.Equal(tags, GetProductTestFactory("en-US", "en-US").LoadById(long.GetData("entity_id")).GetData("tags"))
+	dsn, err := withGroupConcatMaxLen("root@tcp(127.0.0.1:3306)/db?group_concat_max_len=2048")
+	assert.Nil(err)
+	assert.Contains(dsn, "group_concat_max_len=2048")
+}
+
+func TestLocaleFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Fallback").SetData("age", 30).Save()
+	assert.Nil(user.GetLastError())
+	GetUserTestFactory("de-DE", "en-US").LoadById(user.GetData("entity_id")).SetData("name", "Rückfall").Save()
+
+	austrian := GetUserTestFactory("de-AT", "en-US").SetFallbackLocales("de-DE")
+	assert.Equal([]string{"de-AT", "de-DE", "en-US"}, austrian.GetLocaleChain())
+	austrian.LoadById(user.GetData("entity_id"))
+	assert.Equal("Rückfall", austrian.GetData("name"))
+	assert.Equal("de-DE", austrian.GetDataLocale("name"))
+	austrian.SetData("name", "Österreich")
+	assert.Equal("de-AT", austrian.GetDataLocale("name"))
+
+	swiss := GetUserTestFactory("de-CH", "en-US").LoadById(user.GetData("entity_id"))
+	assert.Equal("Fallback", swiss.GetData("name"))
+	assert.Equal("en-US", swiss.GetDataLocale("name"))
+	assert.Equal("", swiss.GetDataLocale("age"))
+
+	collection := GetUserTestCollectionFactory("de-AT", "en-US").SetFallbackLocales("de-DE")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "Rückfall"}})
+	assert.Len(collection.GetElems(), 1)
+	assert.Equal("de-DE", collection.GetElems()[0].GetDataLocale("name"))
+	assert.Equal([]string{"de-DE"}, collection.GetElems()[0].GetFallbackLocales())
+}
+
+func TestEavScope(t *testing.T) {
+	assert := assert.New(t)
+
+	store := EavScope{Type: "store", Id: 3}
+	website := EavScope{Type: "website", Id: 1}
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Global").Save()
+	assert.Nil(user.GetLastError())
+	GetUserTestFactory("en-US", "en-US").LoadById(user.GetData("entity_id")).SetSaveScope(website).SetData("name", "Website").Save()
+
+	// scope 保存在 value 表的 scope 字段
+	connection := user.GetConnection()
+	assert.Equal(1, ConvertToInt(connection.FetchOne(connection.Expr("select count(*) from user_varchar where entity_id = ? and locale = 'en-US' and scope = 'website:1'", user.GetData("entity_id")))))
+
+	// 註冊上一級後從一個 scope 展開
+	RegisterEavScopeParent("store", func(id uint64) EavScope { return website })
+	assert.Equal([]EavScope{store, website}, ResolveEavScopes(store))
+	assert.Equal([]EavScope{website}, ResolveEavScopes(website))
+	assert.Empty(ResolveEavScopes(EavScope{}))
+	model := GetUserTestFactory("en-US", "en-US").SetEavScope(store).SetSaveScope(store)
+	assert.Equal([]string{"en-US@store:3", "en-US@website:1", "en-US"}, model.GetValueLocaleChain())
+	model.LoadById(user.GetData("entity_id"))
+	assert.Equal("Website", model.GetData("name"))
+	assert.Equal("en-US@website:1", model.GetDataLocale("name"))
+	model.SetData("name", "Store").Save()
+	assert.Nil(model.GetLastError())
+
+	model2 := GetUserTestFactory("en-US", "en-US").SetEavScopes(store, website).LoadById(user.GetData("entity_id"))
+	assert.Equal("Store", model2.GetData("name"))
+	locale, scope := ParseScopedLocale(model2.GetDataLocale("name"))
+	assert.Equal("en-US", locale)
+	assert.Equal(store, scope)
+	assert.Equal("Global", GetUserTestFactory("en-US", "en-US").LoadById(user.GetData("entity_id")).GetData("name"))
+
+	model2.SetSaveScope(store).UseDefault("name")
+	assert.Nil(model2.GetLastError())
+	assert.Equal("Website", model2.GetData("name"))
+	assert.Equal("en-US@website:1", model2.GetDataLocale("name"))
+
+	collection := GetUserTestCollectionFactory("en-US", "en-US").SetEavScopes(website)
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "Website"}})
+	assert.Len(collection.GetElems(), 1)
+
+	// 帶 scope 的值不在 LoadAllLocales 中， 跟 default locale 一樣時也不會被 PurgeRedundantLocaleValues 刪除
+	assert.Equal(map[string]map[string]interface{}{"en-US": {"name": "Global"}}, model2.LoadAllLocales())
+	GetUserTestFactory("de-DE", "en-US").LoadById(user.GetData("entity_id")).SetSaveScope(website).SetData("name", "Global").Save()
+	_, err := PurgeRedundantLocaleValues(GetUserTestFactory("en-US", "en-US"))
+	assert.Nil(err)
+	assert.Equal("Global", GetUserTestFactory("de-DE", "en-US").SetEavScopes(website).LoadById(user.GetData("entity_id")).GetData("name"))
+	assert.Equal("de-DE@website:1", GetUserTestFactory("de-DE", "en-US").SetEavScopes(website).LoadById(user.GetData("entity_id")).GetDataLocale("name"))
+	// 可以複製帶 scope 的值
+	count, err := CopyLocale(GetUserTestFactory("en-US", "en-US"), "en-US@website:1", "fr-FR@website:1", CopyLocaleOptions{Filter: map[string]map[string]interface{}{"entity_id": {"=": user.GetData("entity_id")}}})
+	assert.Nil(err)
+	assert.Equal(int64(1), count)
+	assert.Equal("Website", GetUserTestFactory("fr-FR", "en-US").SetEavScope(website).LoadById(user.GetData("entity_id")).GetData("name"))
+	assert.Equal("Global", GetUserTestFactory("fr-FR", "en-US").LoadById(user.GetData("entity_id")).GetData("name"))
+}
+
+func TestAllLocales(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "English").SetDataForLocale("zh-CN", "name", "中文").SetDataForLocale("de-DE", "name", "Deutsch").Save()
+	assert.Nil(user.GetLastError())
+	assert.Equal("English", user.GetData("name"))
+
+	locales := user.LoadAllLocales()
+	assert.Nil(user.GetLastError())
+	assert.Equal(map[string]map[string]interface{}{
+		"en-US": {"name": "English"},
+		"zh-CN": {"name": "中文"},
+		"de-DE": {"name": "Deutsch"},
+	}, locales)
+
+	// 主表沒有修改時也保存其他 locale
+	user.SetDataForLocale("zh-CN", "name", "中文2").Save()
+	assert.Nil(user.GetLastError())
+	assert.Equal("中文2", GetUserTestFactory("zh-CN", "en-US").LoadById(user.GetData("entity_id")).GetData("name"))
+
+	product := GetProductTestFactory("en-US", "en-US")
+	product.SetData("sku", "all-locales").SetData("tags", []string{"a", "b"}).SetDataForLocale("zh-CN", "tags", []string{"甲"}).Save()
+	assert.Nil(product.GetLastError())
+	assert.Equal([]string{"甲"}, product.LoadAllLocales()["zh-CN"]["tags"])
+	assert.Equal([]string{"a", "b"}, product.LoadAllLocales()["en-US"]["tags"])
+
+	// 外層事務回滾時保留其他 locale 的值， 下次 Save 再保存
+	db := user.GetConnection().GetDb()
+	err := transaction(testConnectionName, func(connection DBConnectionInterface) {
+		user.GetConnection().SetDb(connection.GetDb())
+		user.SetDataForLocale("fr-FR", "name", "Français").Save()
+		panic(fmt.Errorf("rollback"))
+	})
+	user.GetConnection().SetDb(db)
+	assert.Error(err)
+	assert.Equal("en-US", GetUserTestFactory("fr-FR", "en-US").LoadById(user.GetData("entity_id")).GetDataLocale("name"))
+	user.Save()
+	assert.Nil(user.GetLastError())
+	assert.Equal("Français", GetUserTestFactory("fr-FR", "en-US").LoadById(user.GetData("entity_id")).GetData("name"))
+
+	user.SetDataForLocale("zh-CN", "age", 3).Save()
+	assert.Error(user.GetLastError())
+}
+
+func TestRedundantLocaleValues(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Same").SetDataForLocale("zh-CN", "name", "不同").Save()
+	assert.Nil(user.GetLastError())
+	id := user.GetData("entity_id")
+	countRows := func(locale string) int {
+		connection := user.GetConnection()
+		return ConvertToInt(connection.FetchOne(connection.Expr("select count(*) from user_varchar where entity_id = ? and locale = ?", id, locale)))
+	}
+
+	chinese := GetUserTestFactory("zh-CN", "en-US").LoadById(id)
+	chinese.UnsetLocaleValue("name")
+	assert.Nil(chinese.GetLastError())
+	assert.Equal("Same", chinese.GetData("name"))
+	assert.Equal(0, countRows("zh-CN"))
+
+	german := GetUserTestFactory("de-DE", "en-US").SetDeleteRedundantLocaleValues(true).LoadById(id)
+	german.SetData("name", "Same").SetData("age", 40).Save()
+	assert.Nil(german.GetLastError())
+	assert.Equal(0, countRows("de-DE"))
+	german.SetData("name", "Gleich").SetData("age", 41).Save()
+	assert.Equal(1, countRows("de-DE"))
+	german.SetData("name", nil).SetData("age", 42).Save()
+	assert.Equal(0, countRows("de-DE"))
+
+	// 沒有打開選項時保存 null 和一樣的值
+	GetUserTestFactory("fr-FR", "en-US").LoadById(id).SetData("name", "Same").SetData("age", 43).Save()
+	GetUserTestFactory("it-IT", "en-US").LoadById(id).SetData("name", nil).SetData("age", 44).Save()
+	assert.Equal(1, countRows("fr-FR"))
+	assert.Equal(1, countRows("it-IT"))
+	count, err := PurgeRedundantLocaleValues(GetUserTestFactory("en-US", "en-US"))
+	assert.Nil(err)
+	assert.GreaterOrEqual(count, int64(2))
+	assert.Equal(0, countRows("fr-FR"))
+	assert.Equal(0, countRows("it-IT"))
+	assert.Equal(1, countRows("en-US"))
+
+	// 只有大小寫或者重音不同的值不是多餘的
+	accented := GetUserTestFactory("en-US", "en-US")
+	accented.SetData("name", "Muller").SetDataForLocale("de-DE", "name", "Müller").SetDataForLocale("fr-FR", "name", "muller").Save()
+	assert.Nil(accented.GetLastError())
+	cased := GetUserTestFactory("en-US", "en-US")
+	cased.SetData("name", "Same").SetDataForLocale("fr-FR", "name", "same").Save()
+	assert.Nil(cased.GetLastError())
+	_, err = PurgeRedundantLocaleValues(GetUserTestFactory("en-US", "en-US"))
+	assert.Nil(err)
+	assert.Equal("Müller", GetUserTestFactory("de-DE", "en-US").LoadById(accented.GetData("entity_id")).GetData("name"))
+	assert.Equal("muller", GetUserTestFactory("fr-FR", "en-US").LoadById(accented.GetData("entity_id")).GetData("name"))
+	assert.Equal("same", GetUserTestFactory("fr-FR", "en-US").LoadById(cased.GetData("entity_id")).GetData("name"))
+}
+
+func TestTranslationReport(t *testing.T) {
+	assert := assert.New(t)
+
+	// 用單獨的 attribute， 不受其他測試的數據影響
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "teaser", BackendType: "varchar"}))
+	missing := GetProductTestFactory("en-US", "en-US")
+	missing.SetData("sku", "report-missing").SetData("teaser", "Missing").Save()
+	identical := GetProductTestFactory("en-US", "en-US")
+	identical.SetData("sku", "report-identical").SetData("teaser", "Same").SetDataForLocale("fr-FR", "teaser", "Same").Save()
+	outdated := GetProductTestFactory("en-US", "en-US")
+	outdated.SetData("sku", "report-outdated").SetData("teaser", "Old").SetDataForLocale("fr-FR", "teaser", "Vieux").Save()
+	assert.Nil(outdated.GetLastError())
+	time.Sleep(10 * time.Millisecond)
+	outdated.SetData("teaser", "New").Save()
+
+	options := TranslationReportOptions{Locales: []string{"fr-FR"}, Fields: []string{"teaser"}}
+	report, err := GetTranslationReport(GetProductTestFactory("en-US", "en-US"), options)
+	assert.Nil(err)
+	assert.Equal(int64(3), report.Total)
+	statuses := make(map[string]string)
+	for _, issue := range report.Issues {
+		assert.Equal("fr-FR", issue.Locale)
+		statuses[ConvertToString(issue.EntityId)] = issue.Status
+	}
+	assert.Equal(TranslationMissing, statuses[ConvertToString(missing.GetData("entity_id"))])
+	assert.Equal(TranslationIdentical, statuses[ConvertToString(identical.GetData("entity_id"))])
+	assert.Equal(TranslationOutdated, statuses[ConvertToString(outdated.GetData("entity_id"))])
+
+	options.Statuses = []string{TranslationMissing}
+	report, err = GetTranslationReport(GetProductTestFactory("en-US", "en-US"), options)
+	assert.Nil(err)
+	assert.Equal(int64(1), report.Total)
+
+	options = TranslationReportOptions{Locales: []string{"fr-FR"}, Fields: []string{"teaser"}, Page: 2, PageSize: 2}
+	report, err = GetTranslationReport(GetProductTestFactory("en-US", "en-US"), options)
+	assert.Nil(err)
+	assert.Equal(int64(3), report.Total)
+	assert.Len(report.Issues, 1)
+
+	var buffer strings.Builder
+	assert.Nil(report.WriteCsv(&buffer))
+	assert.True(strings.HasPrefix(buffer.String(), "entity_id,attribute,locale,status"))
+	assert.Len(strings.Split(strings.TrimSpace(buffer.String()), "\n"), 2)
+
+	_, err = GetTranslationReport(GetProductTestFactory("en-US", "en-US"), TranslationReportOptions{Fields: []string{"sku"}})
+	assert.Error(err)
+
+	// 只有大小寫不同的翻譯不是 identical
+	cased := GetProductTestFactory("en-US", "en-US")
+	cased.SetData("sku", "report-cased").SetData("teaser", "Same").SetDataForLocale("de-DE", "teaser", "same").Save()
+	assert.Nil(cased.GetLastError())
+	report, err = GetTranslationReport(GetProductTestFactory("en-US", "en-US"), TranslationReportOptions{Locales: []string{"de-DE"}, Fields: []string{"teaser"}, Statuses: []string{TranslationIdentical}})
+	assert.Nil(err)
+	for _, issue := range report.Issues {
+		assert.NotEqual(ConvertToUint64(cased.GetData("entity_id")), issue.EntityId)
+	}
+
+	// 多值字段沒有修改時保存不會重寫， 翻譯不會變成 outdated
+	tagged := GetProductTestFactory("en-US", "en-US")
+	tagged.SetData("sku", "report-tags").SetData("tags", []string{"red"}).SetDataForLocale("fr-FR", "tags", []string{"rouge"}).Save()
+	assert.Nil(tagged.GetLastError())
+	time.Sleep(10 * time.Millisecond)
+	tagged.SetData("name", "Tagged").Save()
+	assert.Nil(tagged.GetLastError())
+	report, err = GetTranslationReport(GetProductTestFactory("en-US", "en-US"), TranslationReportOptions{Locales: []string{"fr-FR"}, Fields: []string{"tags"}, Statuses: []string{TranslationOutdated}})
+	assert.Nil(err)
+	for _, issue := range report.Issues {
+		assert.NotEqual(ConvertToUint64(tagged.GetData("entity_id")), issue.EntityId)
+	}
+}
+
+func TestTranslationImportExport(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Export me").SetDataForLocale("ja-JP", "name", "古い").Save()
+	assert.Nil(user.GetLastError())
+	id := ConvertToString(user.GetData("entity_id"))
+
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": id}})
+	var csvBuffer strings.Builder
+	assert.Nil(ExportTranslations(collection, &csvBuffer, TranslationExportOptions{Format: TranslationFormatCsv, TargetLocale: "ja-JP"}))
+	assert.Equal("entity_id,attribute,en-US,ja-JP\n"+id+",name,Export me,古い\n", csvBuffer.String())
+
+	var xliffBuffer strings.Builder
+	assert.Nil(ExportTranslations(collection, &xliffBuffer, TranslationExportOptions{Format: TranslationFormatXliff, TargetLocale: "ja-JP"}))
+	assert.Contains(xliffBuffer.String(), `trgLang="ja-JP"`)
+	assert.Contains(xliffBuffer.String(), `<unit id="`+id+`:name">`)
+
+	// SourceLocale 跟 collection 的 locale 不一樣時用 SourceLocale 保存的值
+	var sourceBuffer strings.Builder
+	assert.Nil(ExportTranslations(collection, &sourceBuffer, TranslationExportOptions{Format: TranslationFormatCsv, SourceLocale: "ja-JP", TargetLocale: "de-DE"}))
+	assert.Equal("entity_id,attribute,ja-JP,de-DE\n"+id+",name,古い,\n", sourceBuffer.String())
+	assert.Error(ExportTranslations(collection, &sourceBuffer, TranslationExportOptions{Format: "json", TargetLocale: "ja-JP"}))
+
+	// csv 導入， 包括錯誤的行
+	file := "entity_id,attribute,en-US,ja-JP\n" + id + ",name,Export me,新しい\n0,name,x,y\n" + id + ",age,30,31\n" + id + ",name,Export me,\n"
+	result, err := ImportTranslations(GetUserTestFactory("en-US", "en-US"), strings.NewReader(file), TranslationImportOptions{Format: TranslationFormatCsv, DryRun: true})
+	assert.Nil(err)
+	assert.Equal(1, result.Imported)
+	assert.Equal(1, result.Skipped)
+	assert.Len(result.Errors, 2)
+	assert.Equal(3, result.Errors[0].Line)
+	assert.Equal("unknown attribute", result.Errors[1].Message)
+	assert.Equal("古い", GetUserTestFactory("ja-JP", "en-US").LoadById(id).GetData("name"))
+
+	result, err = ImportTranslations(GetUserTestFactory("en-US", "en-US"), strings.NewReader(file), TranslationImportOptions{Format: TranslationFormatCsv})
+	assert.Nil(err)
+	assert.Equal(1, result.Imported)
+	assert.Equal("新しい", GetUserTestFactory("ja-JP", "en-US").LoadById(id).GetData("name"))
+
+	// xliff 導入
+	xliff := strings.Replace(xliffBuffer.String(), "<target>古い</target>", "<target>XLIFF</target>", 1)
+	result, err = ImportTranslations(GetUserTestFactory("en-US", "en-US"), strings.NewReader(xliff), TranslationImportOptions{Format: TranslationFormatXliff})
+	assert.Nil(err)
+	assert.Equal("ja-JP", result.Locale)
+	assert.Len(result.Errors, 0)
+	assert.Equal("XLIFF", GetUserTestFactory("ja-JP", "en-US").LoadById(id).GetData("name"))
+
+	_, err = ImportTranslations(GetUserTestFactory("en-US", "en-US"), strings.NewReader(""), TranslationImportOptions{Format: TranslationFormatCsv})
+	assert.Error(err)
+}
+
+func TestCopyLocale(t *testing.T) {
+	assert := assert.New(t)
+
+	// IsGlobal 的字段不複製
+	assert.Nil(SaveEavAttribute(testConnectionName, &EavAttribute{EntityType: "product", Code: "copy_weight", BackendType: "int", IsGlobal: true}))
+	first := GetProductTestFactory("en-GB", "en-GB")
+	first.SetData("sku", "copy-1").SetData("name", "Colour").SetData("tags", []string{"a", "b"}).SetData("copy_weight", 5).Save()
+	assert.Nil(first.GetLastError())
+	second := GetProductTestFactory("en-GB", "en-GB")
+	second.SetData("sku", "copy-2").SetData("name", "Flavour").SetDataForLocale("en-AU", "name", "Existing").Save()
+	assert.Nil(second.GetLastError())
+	other := GetProductTestFactory("en-GB", "en-GB")
+	other.SetData("sku", "other").SetData("name", "Other").Save()
+
+	options := CopyLocaleOptions{Filter: map[string]map[string]interface{}{"sku": {"like": "copy-%"}}, ChunkSize: 1}
+	count, err := CopyLocale(GetProductTestFactory("en-GB", "en-GB"), "en-GB", "en-AU", options)
+	assert.Nil(err)
+	assert.Equal(int64(3), count)
+	assert.Equal("Colour", GetProductTestFactory("en-AU", "en-US").LoadById(first.GetData("entity_id")).GetData("name"))
+	assert.Equal([]string{"a", "b"}, GetProductTestFactory("en-AU", "en-US").LoadById(first.GetData("entity_id")).GetData("tags"))
+	assert.Equal("Existing", GetProductTestFactory("en-AU", "en-US").LoadById(second.GetData("entity_id")).GetData("name"))
+	assert.NotEqual("en-AU", GetProductTestFactory("en-AU", "en-US").LoadById(other.GetData("entity_id")).GetDataLocale("name"))
+
+	options.Mode = CopyLocaleOverwrite
+	options.Fields = []string{"name"}
+	_, err = CopyLocale(GetProductTestFactory("en-GB", "en-GB"), "en-GB", "en-AU", options)
+	assert.Nil(err)
+	assert.Equal("Flavour", GetProductTestFactory("en-AU", "en-US").LoadById(second.GetData("entity_id")).GetData("name"))
+
+	_, err = CopyLocale(GetProductTestFactory("en-GB", "en-GB"), "en-GB", "en-GB", CopyLocaleOptions{})
+	assert.Error(err)
+	_, err = CopyLocale(GetProductTestFactory("en-GB", "en-GB"), "en-GB", "en-AU", CopyLocaleOptions{Fields: []string{"copy_weight"}})
+	assert.Error(err)
+	connection := first.GetConnection()
+	assert.Equal(0, ConvertToInt(connection.FetchOne(connection.Expr("select count(*) from product_int where entity_id = ? and attribute_name = 'copy_weight' and locale = 'en-AU'", first.GetData("entity_id")))))
+}
+
+// 嵌入 UserTest 等測試 model 的工廠， 只需要定義 model 的類型
+func testModelFactory(model func() BasicModelInterface) func(locale string, defaultLocale string) Basictablemodelinterface {
+	return func(locale string, defaultLocale string) Basictablemodelinterface {
+		return ModelFactory(func() Basictablemodelinterface {
+			return &Basictablemodel{Model: model(), Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+		})
+	}
+}
+
+func testCollectionFactory(model func() BasicModelInterface) func(locale string, defaultLocale string) CollectionInterface {
+	return func(locale string, defaultLocale string) CollectionInterface {
+		return CollectionFactory(func() Basictablemodelinterface {
+			return &Basictablemodel{Model: model(), Connection: testConnectionName, Locale: locale, DefaultLocale: defaultLocale}
+		})
+	}
+}
+
+type FlatUserTest struct {
+	UserTest
+}
+
+func (e *FlatUserTest) IsFlatIndexed() bool {
+	return true
+}
+
+var (
+	GetFlatUserTestFactory           = testModelFactory(func() BasicModelInterface { return &FlatUserTest{} })
+	GetFlatUserTestCollectionFactory = testCollectionFactory(func() BasicModelInterface { return &FlatUserTest{} })
+)
+
+func TestFlatIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetFlatUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Flat").SetDataForLocale("zh-CN", "name", "扁平").Save()
+	assert.Nil(user.GetLastError())
+	assert.Equal("user_flat_zh_cn", FlatTableName("user", "zh-CN"))
+	assert.Nil(ReindexFlat(GetFlatUserTestFactory("en-US", "en-US"), "en-US", "zh-CN"))
+
+	collection := GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "扁平"}})
+	assert.Len(collection.GetElems(), 1)
+	sql, _ := collection.GetSelect().Assemble()
+	assert.Contains(sql, "user_flat_zh_cn")
+
+	// Save 和 Delete 增量更新
+	user.SetDataForLocale("zh-CN", "name", "扁平2").Save()
+	assert.Nil(user.GetLastError())
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "扁平2"}})
+	assert.Len(collection.GetElems(), 1)
+	sql, _ = collection.GetSelect().Assemble()
+	assert.Contains(sql, "user_flat_zh_cn")
+	other := GetFlatUserTestFactory("en-US", "en-US")
+	other.SetData("name", "Flat other").Save()
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "Flat other"}})
+	assert.Len(collection.GetElems(), 1)
+	other.Delete()
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "Flat other"}})
+	assert.Len(collection.GetElems(), 0)
+
+	// UseDefault 也更新 flat 表
+	GetFlatUserTestFactory("zh-CN", "en-US").LoadById(user.GetData("entity_id")).UnsetLocaleValue("name")
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": user.GetData("entity_id")}})
+	assert.Equal("Flat", collection.GetElems()[0].GetData("name"))
+	sql, _ = collection.GetSelect().Assemble()
+	assert.Contains(sql, "user_flat_zh_cn")
+
+	// 從 flat 表讀取時不延遲加載 eav 字段
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToSelect("age")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": user.GetData("entity_id")}})
+	assert.Len(collection.GetElems(), 1)
+	sql, _ = collection.GetSelect().Assemble()
+	assert.Contains(sql, "user_flat_zh_cn")
+	assert.Contains(sql, "e.name")
+	assert.Nil(collection.GetElems()[0].(*Basictablemodel).lazyEav)
+	assert.Equal("Flat", collection.GetElems()[0].GetData("name"))
+
+	// 沒有 flat 表或者過期時 join value 表
+	collection = GetFlatUserTestCollectionFactory("de-DE", "en-US")
+	collection.Load()
+	sql, _ = collection.GetSelect().Assemble()
+	assert.NotContains(sql, "user_flat")
+	assert.Nil(InvalidateFlatIndex(GetFlatUserTestFactory("en-US", "en-US")))
+	collection = GetFlatUserTestCollectionFactory("zh-CN", "en-US")
+	collection.Load()
+	sql, _ = collection.GetSelect().Assemble()
+	assert.NotContains(sql, "user_flat")
+}
+
+type PivotUserTest struct {
+	UserTest
+}
+
+func (e *PivotUserTest) GetEavLoadStrategy() string {
+	return EavLoadStrategyPivot
+}
+
+var (
+	GetPivotUserTestFactory           = testModelFactory(func() BasicModelInterface { return &PivotUserTest{} })
+	GetPivotUserTestCollectionFactory = testCollectionFactory(func() BasicModelInterface { return &PivotUserTest{} })
+)
+
+func TestPivotLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetPivotUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Pivot").SetData("age", 41).SetDataForLocale("zh-CN", "name", "樞軸").Save()
+	assert.Nil(user.GetLastError())
+	id := user.GetData("entity_id")
+
+	loaded := GetPivotUserTestFactory("zh-CN", "en-US").LoadById(id)
+	assert.Equal("樞軸", loaded.GetData("name"))
+	assert.Equal("zh-CN", loaded.GetDataLocale("name"))
+	loaded = GetPivotUserTestFactory("de-DE", "en-US").LoadById(id)
+	assert.Equal("Pivot", loaded.GetData("name"))
+	assert.Equal("en-US", loaded.GetDataLocale("name"))
+
+	// 結果和 join 一樣， 主表 sql 沒有 join
+	collection := GetPivotUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"age": {"=": 41}})
+	elems := collection.GetElems()
+	sql, _ := collection.GetSelect().Assemble()
+	assert.NotContains(sql, "left join")
+	joined := GetPivotUserTestCollectionFactory("zh-CN", "en-US").SetEavLoadStrategy(EavLoadStrategyJoin)
+	joined.AddFieldToFilter(map[string]map[string]interface{}{"age": {"=": 41}})
+	assert.Equal(len(joined.GetElems()), len(elems))
+	for i, elem := range joined.GetElems() {
+		assert.Equal(elem.GetData("name"), elems[i].GetData("name"))
+	}
+
+	// 過濾 eav 字段時用 join
+	collection = GetPivotUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "樞軸"}})
+	assert.Len(collection.GetElems(), 1)
+	sql, _ = collection.GetSelect().Assemble()
+	assert.Contains(sql, "left join")
+
+	// 選擇 eav 字段時不從主表選擇 eav 字段的列
+	collection = GetPivotUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToSelect("name")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": id}})
+	elems = collection.GetElems()
+	assert.Nil(collection.GetLastError())
+	assert.Len(elems, 1)
+	assert.Equal("樞軸", elems[0].GetData("name"))
+	sql, _ = collection.GetSelect().Assemble()
+	assert.NotContains(sql, "left join")
+	assert.NotContains(sql, "e.name")
+}
+
+// b.N 變化時 benchmark 函數會執行多次， 數據只寫入一次
+var benchmarkSeedOnce sync.Once
+
+func benchmarkCollectionLoad(b *testing.B, strategy string) {
+	benchmarkSeedOnce.Do(func() {
+		for i := 0; i < 20; i++ {
+			GetUserTestFactory("en-US", "en-US").SetData("name", fmt.Sprintf("bench %d", i)).SetData("age", 77).Save()
+		}
+	})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		collection := GetUserTestCollectionFactory("zh-CN", "en-US").SetEavLoadStrategy(strategy)
+		collection.AddFieldToFilter(map[string]map[string]interface{}{"age": {"=": 77}})
+		collection.Load()
+	}
+}
+
+func BenchmarkCollectionLoadJoin(b *testing.B) {
+	benchmarkCollectionLoad(b, EavLoadStrategyJoin)
+}
+
+func BenchmarkCollectionLoadPivot(b *testing.B) {
+	benchmarkCollectionLoad(b, EavLoadStrategyPivot)
+}
+
+func TestLazyEavFields(t *testing.T) {
+	assert := assert.New(t)
+
+	user := GetUserTestFactory("en-US", "en-US")
+	user.SetData("name", "Lazy").SetData("age", 52).SetDataForLocale("zh-CN", "name", "懶").Save()
+	assert.Nil(user.GetLastError())
+
+	// 只選擇主表字段時不 join name， 第一次 GetData 時加載
+	collection := GetUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToSelect("age")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"entity_id": {"=": user.GetData("entity_id")}})
+	elems := collection.GetElems()
+	assert.Len(elems, 1)
+	sql, _ := collection.GetSelect().Assemble()
+	assert.NotContains(sql, "e_name")
+	assert.Contains(sql, "e.entity_id")
+	assert.Equal("懶", elems[0].GetData("name"))
+	assert.Equal("zh-CN", elems[0].GetDataLocale("name"))
+
+	// 過濾的 eav 字段需要 join
+	collection = GetUserTestCollectionFactory("zh-CN", "en-US")
+	collection.AddFieldToSelect("age")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"=": "懶"}})
+	assert.Len(collection.GetElems(), 1)
+	sql, _ = collection.GetSelect().Assemble()
+	assert.Contains(sql, "e_name")
+}
+
+func TestSelectJoinColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	// join 的列名跟別名不一樣時才加 as
+	dbselect := &DBSelect{}
+	dbselect.From("user", "e", map[string]string{})
+	dbselect.LeftJoin("g", "user_group", "g.group_id = e.group_id", map[string]string{"group_name": "name"})
+	dbselect.LeftJoin("r", "user_role", "r.entity_id = e.entity_id", map[string]string{"code": "code"})
+	sql, err := dbselect.Assemble()
+	assert.Nil(err)
+	assert.Contains(sql, "g.name as group_name")
+	assert.Contains(sql, "r.code")
+	assert.NotContains(sql, "r.code as code")
+}
+
+func TestCollectionFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Filter A").SetData("age", 61).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Filter B").SetData("age", 61).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Filter C").SetData("age", 62).Save()
+
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	collection.Filter(And(
+		FilterField("age").In([]int{61, 62}),
+		Or(FilterField("name").Eq("Filter A"), Not(FilterField("name").Like("Filter %"))),
+	))
+	elems := collection.GetElems()
+	assert.Nil(collection.GetLastError())
+	assert.Len(elems, 1)
+	assert.Equal("Filter A", elems[0].GetData("name"))
+
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.Filter(Or(FilterField("age").Gte(62), And(FilterField("age").Eq(61), FilterField("name").Neq("Filter A"))))
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"like": "Filter %"}})
+	assert.Equal(2, collection.GetSize())
+
+	// 未知字段
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.Filter(FilterField("age; drop table user").Eq(1))
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
+
+func TestCollectionQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Query john").SetData("age", 71).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Query joe").SetData("age", 72).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Query ann").SetData("age", 73).Save()
+
+	options := QueryOptions{Filterable: []string{"age", "name"}, Sortable: []string{"age"}}
+	values, _ := url.ParseQuery("filter[age][gte]=71&filter[name][like]=Query jo%25&sort=-age&page[size]=1&page[number]=2")
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	elems := collection.GetElems()
+	assert.Nil(collection.GetLastError())
+	assert.Len(elems, 1)
+	assert.Equal("Query john", elems[0].GetData("name"))
+	assert.Equal(2, collection.GetSize())
+
+	values, _ = url.ParseQuery("filter=age>=71,age<=73,name~ann")
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	assert.Equal(1, collection.GetSize())
+
+	values, _ = url.ParseQuery("filter[age][in]=71,73")
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	assert.Equal(2, collection.GetSize())
+
+	// 重複的 filter 之間是 and， sort 和 page 重複時返回錯誤
+	values, _ = url.ParseQuery("filter[age][gte]=71&filter[age][gte]=72&filter[name][like]=Query %25")
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	assert.Equal(2, collection.GetSize())
+	values, _ = url.ParseQuery("filter=age>=71&filter=age<=71")
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	assert.Equal(1, collection.GetSize())
+	values, _ = url.ParseQuery("sort=age&sort=-age")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, options))
+	values, _ = url.ParseQuery("page[size]=1&page[size]=100")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, options))
+
+	// 白名單和未知操作符， 沒有白名單時不能過濾和排序
+	values, _ = url.ParseQuery("filter[password][eq]=1")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, options))
+	values, _ = url.ParseQuery("filter[age][eq]=71")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, QueryOptions{}))
+	_, err := ParseFilterString(GetUserTestFactory("en-US", "en-US"), "age>=71", QueryOptions{})
+	assert.NotNil(err)
+	values, _ = url.ParseQuery("sort=age")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, QueryOptions{}))
+	values, _ = url.ParseQuery("page[size]=1")
+	assert.Nil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, QueryOptions{}))
+	values, _ = url.ParseQuery("filter[age][xor]=1")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, options))
+	values, _ = url.ParseQuery("filter[age][between]=18")
+	_, err = ParseCollectionQuery(GetUserTestFactory("en-US", "en-US"), values, options)
+	assert.NotNil(err)
+	values, _ = url.ParseQuery("filter[name][match]=john")
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	assert.Nil(collection.ApplyQuery(values, options))
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+	values, _ = url.ParseQuery("sort=name")
+	assert.NotNil(GetUserTestCollectionFactory("en-US", "en-US").ApplyQuery(values, options))
+	_, err = ParseFilterString(GetUserTestFactory("en-US", "en-US"), "age^18", options)
+	assert.NotNil(err)
+}
+
+func TestFilterOperators(t *testing.T) {
+	assert := assert.New(t)
+
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Op 100%_off").SetData("age", 81).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Op 100xyoff").SetData("age", 82).Save()
+	GetUserTestFactory("en-US", "en-US").SetData("name", "Op plain").SetData("age", 83).Save()
+
+	count := func(filters map[string]map[string]interface{}) int {
+		collection := GetUserTestCollectionFactory("en-US", "en-US")
+		collection.AddFieldToFilter(filters)
+		return collection.GetSize()
+	}
+	assert.Equal(1, count(map[string]map[string]interface{}{"name": {"like": "%100%_%"}}))
+	assert.Equal(1, count(map[string]map[string]interface{}{"name": {"contains": "%_"}}))
+	assert.Equal(3, count(map[string]map[string]interface{}{"name": {"starts": "Op "}}))
+	assert.Equal(2, count(map[string]map[string]interface{}{"name": {"ends": "off"}}))
+	assert.Equal(2, count(map[string]map[string]interface{}{"age": {"between": []int{81, 82}}}))
+	assert.Equal(1, count(map[string]map[string]interface{}{"age": {"between": []int{81, 83}, "nbetween": []int{81, 82}}}))
+	assert.Equal(2, count(map[string]map[string]interface{}{"name": {"regexp": "^Op 100"}}))
+	assert.Equal(1, count(map[string]map[string]interface{}{"age": {"finset": "83"}}))
+
+	// 未知操作符返回錯誤
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"age": {"= 1 or 1 =": 1}})
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"age": {"between": 81}})
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+	user := GetUserTestFactory("en-US", "en-US").LoadOneByFilter(map[string]map[string]interface{}{"age": {"xor": 1}})
+	assert.NotNil(user.GetLastError())
+
+	// match 用 address.postcode 的 FULLTEXT 索引， eav 字段返回錯誤
+	owner := GetUserTestFactory("en-US", "en-US")
+	owner.SetData("name", "Op fulltext").SetData("age", 84).Save()
+	address := GetAddressTestFactory("en-US", "en-US")
+	address.SetData("user_id", owner.GetData("entity_id")).SetData("postcode", "fulltextharbour").SetData("city", "Harbour").Save()
+	assert.Nil(address.GetLastError())
+	found := GetAddressTestFactory("en-US", "en-US").LoadOneByFilter(map[string]map[string]interface{}{"postcode": {"match": "fulltextharbour"}})
+	assert.Nil(found.GetLastError())
+	assert.Equal(address.GetData("entity_id"), found.GetData("entity_id"))
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddRelationFilter("addresses", map[string]map[string]interface{}{"postcode": {"match": "fulltextharbour"}})
+	assert.Len(collection.GetElems(), 1)
+	found = GetAddressTestFactory("en-US", "en-US").LoadOneByFilter(map[string]map[string]interface{}{"city": {"match": "Harbour"}})
+	assert.NotNil(found.GetLastError())
+	collection = GetUserTestCollectionFactory("en-US", "en-US")
+	collection.Filter(FilterField("name").Match("fulltext"))
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
+
+type ScopedUserTest struct {
+	UserTest
+}
+
+func (e *ScopedUserTest) GetScopes() map[string]func(CollectionInterface, ...interface{}) {
+	return map[string]func(CollectionInterface, ...interface{}){
+		"active": func(collection CollectionInterface, args ...interface{}) {
+			collection.AddFieldToFilter(map[string]map[string]interface{}{"is_active": {"=": 1}})
+		},
+		"older_than": func(collection CollectionInterface, args ...interface{}) {
+			collection.Filter(FilterField("age").Gt(args[0]))
+		},
+	}
+}
+
+func (e *ScopedUserTest) GetDefaultScopes() []string {
+	return []string{"active"}
+}
+
+var (
+	GetScopedUserTestFactory           = testModelFactory(func() BasicModelInterface { return &ScopedUserTest{} })
+	GetScopedUserTestCollectionFactory = testCollectionFactory(func() BasicModelInterface { return &ScopedUserTest{} })
+)
+
+type ScopedUserAddressTest struct {
+	AddressTest
+}
+
+func (e *ScopedUserAddressTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"user": {Type: RelationBelongsTo, Factory: GetScopedUserTestFactory, ForeignKey: "user_id"},
+	}
+}
+
+var GetScopedUserAddressTestCollectionFactory = testCollectionFactory(func() BasicModelInterface { return &ScopedUserAddressTest{} })
+
+func TestModelScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	active := GetScopedUserTestFactory("en-US", "en-US")
+	active.SetData("name", "Scoped active").SetData("age", 91).SetData("is_active", true).Save()
+	inactive := GetScopedUserTestFactory("en-US", "en-US")
+	inactive.SetData("name", "Scoped inactive").SetData("age", 92).SetData("is_active", false).Save()
+	assert.Nil(inactive.GetLastError())
+
+	filter := map[string]map[string]interface{}{"name": {"starts": "Scoped "}}
+	collection := GetScopedUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(filter)
+	assert.Equal(1, collection.GetSize())
+	collection = GetScopedUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(filter).WithoutDefaultScopes("active")
+	assert.Equal(2, collection.GetSize())
+	collection = GetScopedUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(filter).WithoutDefaultScopes().Scope("older_than", 91)
+	assert.Equal(1, collection.GetSize())
+
+	// LoadByField 也應用默認 scope
+	loaded := GetScopedUserTestFactory("en-US", "en-US").LoadById(inactive.GetData("entity_id"))
+	assert.Nil(loaded.GetLastError())
+	assert.Nil(loaded.GetData("entity_id"))
+	loaded = GetScopedUserTestFactory("en-US", "en-US").WithoutDefaultScopes().LoadById(inactive.GetData("entity_id"))
+	assert.Equal("Scoped inactive", loaded.GetData("name"))
+
+	// LoadByFields 和 LoadOneByFilter 一樣
+	loaded = GetScopedUserTestFactory("en-US", "en-US").LoadByFields(map[string]interface{}{"name": "Scoped inactive"})
+	assert.ErrorIs(loaded.GetLastError(), ErrNotFound)
+	loaded = GetScopedUserTestFactory("en-US", "en-US").LoadOneByFilter(filter)
+	assert.Nil(loaded.GetLastError())
+	assert.Equal("Scoped active", loaded.GetData("name"))
+	loaded = GetScopedUserTestFactory("en-US", "en-US").WithoutDefaultScopes().LoadOneByFilter(filter)
+	assert.ErrorIs(loaded.GetLastError(), ErrMultipleResults)
+
+	// 關聯過濾也應用關聯 model 的默認 scope
+	for index, user := range []Basictablemodelinterface{active, inactive} {
+		address := GetAddressTestFactory("en-US", "en-US")
+		address.SetData("user_id", user.GetData("entity_id")).SetData("postcode", fmt.Sprintf("scoped-%d", index)).Save()
+		assert.Nil(address.GetLastError())
+	}
+	addresses := GetScopedUserAddressTestCollectionFactory("en-US", "en-US")
+	addresses.AddFieldToFilter(map[string]map[string]interface{}{"postcode": {"starts": "scoped-"}}).AddRelationFilter("user", nil)
+	assert.Len(addresses.GetElems(), 1)
+	assert.Equal("scoped-0", addresses.GetElems()[0].GetData("postcode"))
+	addresses = GetScopedUserAddressTestCollectionFactory("en-US", "en-US")
+	addresses.AddFieldToFilter(map[string]map[string]interface{}{"postcode": {"starts": "scoped-"}}).AddRelationCountFilter("user", nil, "=", 0)
+	assert.Len(addresses.GetElems(), 1)
+	assert.Equal("scoped-1", addresses.GetElems()[0].GetData("postcode"))
+
+	collection = GetScopedUserTestCollectionFactory("en-US", "en-US").Scope("unknown")
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
+
+func TestCollectionAggregate(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, item := range []struct {
+		name string
+		age  int
+	}{{"Agg Paris", 101}, {"Agg Paris", 103}, {"Agg Berlin", 110}} {
+		user := GetUserTestFactory("en-US", "en-US")
+		user.SetData("name", item.name).SetData("age", item.age).Save()
+		assert.Nil(user.GetLastError())
+	}
+
+	collection := GetUserTestCollectionFactory("en-US", "en-US")
+	collection.AddFieldToFilter(map[string]map[string]interface{}{"name": {"starts": "Agg "}})
+	rows, err := collection.Aggregate().Count("", "cnt").Avg("age", "avg_age").Max("age", "max_age").GroupBy("name").Fetch()
+	assert.Nil(err)
+	assert.Len(rows, 2)
+	assert.Equal("Agg Berlin", rows[0].String("name"))
+	assert.Equal(int64(1), rows[0].Int("cnt"))
+	assert.Equal("Agg Paris", rows[1].String("name"))
+	assert.Equal(int64(2), rows[1].Int("cnt"))
+	assert.Equal(102.0, rows[1].Float("avg_age"))
+	assert.Equal(int64(103), rows[1].Int("max_age"))
+
+	rows, err = collection.Aggregate().Count("", "cnt").GroupBy("name").Having("cnt > ?", 1).Fetch()
+	assert.Nil(err)
+	assert.Len(rows, 1)
+	rows, err = collection.Aggregate().Sum("age", "total").Min("age", "min_age").Fetch()
+	assert.Nil(err)
+	assert.Equal(314.0, rows[0].Float("total"))
+	assert.Equal(int64(101), rows[0].Int("min_age"))
+
+	_, err = collection.Aggregate().Sum("unknown", "total").Fetch()
+	assert.NotNil(err)
+	_, err = collection.Aggregate().Sum("age", "total; drop table user").Fetch()
+	assert.NotNil(err)
+
+	// 聚合不修改 collection 的條件、 用到的字段和默認 scope
+	scoped := GetScopedUserTestCollectionFactory("en-US", "en-US")
+	scoped.AddFieldToFilter(map[string]map[string]interface{}{"name": {"starts": "Agg "}})
+	c := scoped.(*Collection)
+	where := append([]string(nil), c.DbSelect.(*DBSelect)._where...)
+	usedFields := len(c.UsedFields)
+	_, err = scoped.Aggregate().Count("", "cnt").Sum("age", "total").GroupBy("age").GetSql()
+	assert.Nil(err)
+	assert.Equal(where, c.DbSelect.(*DBSelect)._where)
+	assert.Len(c.UsedFields, usedFields)
+	assert.False(c.defaultScopesApplied)
+	scoped.GetSize()
+	assert.Nil(scoped.GetLastError())
+}
+
+type NoFactoryRelationUserTest struct {
+	UserTest
+}
+
+func (e *NoFactoryRelationUserTest) GetRelations() map[string]Relation {
+	return map[string]Relation{
+		"addresses": {Type: RelationHasMany, ForeignKey: "user_id"},
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NotNil(RegisterModel("broken", nil, GetUserTestFactory))
+	assert.NotNil(RegisterModel("broken", &UserTest{}, nil))
+	_, ok := GetRegisteredModelFactory("broken")
+	assert.False(ok)
+
+	// 沒有 Factory 也不是 Self 的關聯返回錯誤
+	collection := testCollectionFactory(func() BasicModelInterface { return &NoFactoryRelationUserTest{} })("en-US", "en-US").With("addresses")
+	collection.Load()
+	assert.NotNil(collection.GetLastError())
+}
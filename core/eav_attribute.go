@@ -0,0 +1,230 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// eav_attribute.scope 字段的值
+const (
+	eavAttributeScopeGlobal = "global"
+	eavAttributeScopeLocale = "locale"
+)
+
+// Field.Input， select 保存 option id， multiselect 保存逗號分隔的 option id
+const (
+	InputSelect      = "select"
+	InputMultiselect = "multiselect"
+)
+
+// 保存在 eav_attribute 表中的 eav 字段， 不需要在 GetTableFields 中定義
+// EntityType: 主表名字， 跟 GetTableName 一樣
+type EavAttribute struct {
+	AttributeId uint64
+	EntityType  string
+	Code        string
+	BackendType string
+	Input       string // 空， InputSelect 或者 InputMultiselect
+	IsRequired  bool
+	IsGlobal    bool // 所有 locale 共用， 保存在 default locale， 跟 Field.IsGlobal 一樣
+	SortOrder   int
+	Labels      map[string]string // locale => label
+}
+
+func (a EavAttribute) GetLabel(locale string, defaultLocale string) string {
+	if label, ok := a.Labels[locale]; ok && label != "" {
+		return label
+	}
+	if label, ok := a.Labels[defaultLocale]; ok && label != "" {
+		return label
+	}
+	return a.Code
+}
+
+func (a EavAttribute) ToField() Field {
+	backend, _ := GetEavBackend(a.BackendType)
+	return Field{IsEav: true, DbType: backend.DbType, EavType: a.BackendType, Input: a.Input, IsRequired: a.IsRequired, IsGlobal: a.IsGlobal}
+}
+
+var eavAttributeCache = make(map[string][]EavAttribute)
+var eavAttributeCacheLock sync.RWMutex
+
+func eavAttributeCacheKey(connectionName string, entityType string) string {
+	return connectionName + "|" + entityType
+}
+
+// 多個進程時， 其他進程修改了 eav_attribute 後需要清空緩存
+func ClearEavAttributeCache() {
+	eavAttributeCacheLock.Lock()
+	defer eavAttributeCacheLock.Unlock()
+	eavAttributeCache = make(map[string][]EavAttribute)
+}
+
+func clearEavAttributeCache(connectionName string, entityType string) {
+	eavAttributeCacheLock.Lock()
+	defer eavAttributeCacheLock.Unlock()
+	delete(eavAttributeCache, eavAttributeCacheKey(connectionName, entityType))
+}
+
+func newConnection(connectionName string) DBConnectionInterface {
+	connection := &DBConnection{}
+	connection.Init(connectionName)
+	if connection.GetDb() == nil {
+		panic(fmt.Errorf("connection %s not found", connectionName))
+	}
+	return connection
+}
+
+// connection 是否在事務中， 事務中讀取的結果可能回滾， 不能緩存
+func inTransaction(connection DBConnectionInterface) bool {
+	db := connection.GetDb()
+	if db == nil || db.Statement == nil {
+		return false
+	}
+	tx, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && tx != nil
+}
+
+// 在 connectionName 的事務中執行 callback， callback 中 panic 時回滾
+func transaction(connectionName string, callback func(connection DBConnectionInterface)) error {
+	return recoverError(func() {
+		err := newConnection(connectionName).GetDb().Transaction(func(tx *gorm.DB) error {
+			return recoverError(func() {
+				connection := &DBConnection{}
+				connection.SetDb(tx)
+				callback(connection)
+			})
+		})
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+// 返回 entity type 的所有 runtime eav 字段， 按 sort_order 排序， 結果會緩存
+func GetEavAttributes(connectionName string, entityType string) ([]EavAttribute, error) {
+	return getEavAttributes(nil, connectionName, entityType)
+}
+
+// connection 在事務中時用它查詢， 例如 model 所在的事務， 結果可能回滾， 不緩存
+// 其他時候用 newConnection 查詢並緩存
+func getEavAttributes(connection DBConnectionInterface, connectionName string, entityType string) ([]EavAttribute, error) {
+	cache := connection == nil || !inTransaction(connection)
+	key := eavAttributeCacheKey(connectionName, entityType)
+	eavAttributeCacheLock.RLock()
+	attributes, ok := eavAttributeCache[key]
+	eavAttributeCacheLock.RUnlock()
+	if ok {
+		return attributes, nil
+	}
+
+	err := recoverError(func() {
+		if cache {
+			connection = newConnection(connectionName)
+		}
+		rows := connection.Fetch(connection.Expr("select * from eav_attribute where entity_type = ? order by sort_order, attribute_id", entityType))
+		labels := connection.Fetch(connection.Expr("select l.* from eav_attribute_label as l inner join eav_attribute as a on a.attribute_id = l.attribute_id where a.entity_type = ?", entityType))
+		attributes = make([]EavAttribute, 0, len(rows))
+		for _, row := range rows {
+			attribute := EavAttribute{
+				AttributeId: ConvertToUint64(row["attribute_id"]),
+				EntityType:  ConvertToString(row["entity_type"]),
+				Code:        ConvertToString(row["attribute_code"]),
+				BackendType: ConvertToString(row["backend_type"]),
+				Input:       ConvertToString(row["frontend_input"]),
+				IsRequired:  ConvertToBool(row["is_required"]),
+				IsGlobal:    ConvertToString(row["scope"]) == eavAttributeScopeGlobal,
+				SortOrder:   ConvertToInt(row["sort_order"]),
+				Labels:      make(map[string]string),
+			}
+			for _, label := range labels {
+				if ConvertToUint64(label["attribute_id"]) == attribute.AttributeId {
+					attribute.Labels[ConvertToString(label["locale"])] = ConvertToString(label["label"])
+				}
+			}
+			attributes = append(attributes, attribute)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !cache {
+		return attributes, nil
+	}
+	eavAttributeCacheLock.Lock()
+	eavAttributeCache[key] = attributes
+	eavAttributeCacheLock.Unlock()
+	return attributes, nil
+}
+
+func GetEavAttribute(connectionName string, entityType string, code string) (EavAttribute, bool, error) {
+	attributes, err := GetEavAttributes(connectionName, entityType)
+	if err != nil {
+		return EavAttribute{}, false, err
+	}
+	for _, attribute := range attributes {
+		if attribute.Code == code {
+			return attribute, true, nil
+		}
+	}
+	return EavAttribute{}, false, nil
+}
+
+// 新增或者更新 (按 entity type + code)， Labels 會整個替換
+func SaveEavAttribute(connectionName string, attribute *EavAttribute) error {
+	attribute.Code = strings.ToLower(attribute.Code)
+	if attribute.EntityType == "" || attribute.Code == "" {
+		return fmt.Errorf("entity type and code are required")
+	}
+	if _, ok := GetEavBackend(attribute.BackendType); !ok {
+		return fmt.Errorf("unknown backend type %s", attribute.BackendType)
+	}
+	if attribute.Input != "" && attribute.Input != InputSelect && attribute.Input != InputMultiselect {
+		return fmt.Errorf("unknown input %s", attribute.Input)
+	}
+
+	scope := eavAttributeScopeLocale
+	if attribute.IsGlobal {
+		scope = eavAttributeScopeGlobal
+	}
+
+	err := transaction(connectionName, func(connection DBConnectionInterface) {
+		connection.InsertMultiOnUpdate("eav_attribute", []map[string]interface{}{{
+			"entity_type":    attribute.EntityType,
+			"attribute_code": attribute.Code,
+			"backend_type":   attribute.BackendType,
+			"frontend_input": attribute.Input,
+			"is_required":    attribute.IsRequired,
+			"scope":          scope,
+			"sort_order":     attribute.SortOrder,
+		}})
+		attribute.AttributeId = ConvertToUint64(connection.FetchOne(connection.Expr("select attribute_id from eav_attribute where entity_type = ? and attribute_code = ?", attribute.EntityType, attribute.Code)))
+		connection.Delete("eav_attribute_label", connection.Expr("attribute_id = ?", attribute.AttributeId))
+		locales := make([]string, 0, len(attribute.Labels))
+		for locale := range attribute.Labels {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+		labels := make([]map[string]interface{}, 0, len(locales))
+		for _, locale := range locales {
+			labels = append(labels, map[string]interface{}{"attribute_id": attribute.AttributeId, "locale": locale, "label": attribute.Labels[locale]})
+		}
+		connection.InsertMulti("eav_attribute_label", labels)
+	})
+	clearEavAttributeCache(connectionName, attribute.EntityType)
+	return err
+}
+
+// 只刪除字段定義， value 表中的值不會刪除
+func DeleteEavAttribute(connectionName string, entityType string, code string) error {
+	err := recoverError(func() {
+		connection := newConnection(connectionName)
+		connection.Delete("eav_attribute", connection.Expr("entity_type = ? and attribute_code = ?", entityType, strings.ToLower(code)))
+	})
+	clearEavAttributeCache(connectionName, entityType)
+	return err
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// eav value 表的類型， 表名是 <主表>_<Type>， 例如 user_int
+// SqlType: value 字段的類型， DbType: 沒有設置 Field.DbType 時 Convert 用的類型
+type EavBackend struct {
+	Type    string
+	SqlType string
+	DbType  string
+}
+
+var eavBackends = map[string]EavBackend{
+	"varchar":  {Type: "varchar", SqlType: "varchar(255)", DbType: "string"},
+	"text":     {Type: "text", SqlType: "mediumtext", DbType: "string"},
+	"int":      {Type: "int", SqlType: "bigint", DbType: "int64"},
+	"decimal":  {Type: "decimal", SqlType: "decimal(20,6)", DbType: "float64"},
+	"datetime": {Type: "datetime", SqlType: "datetime(3)", DbType: "time.Time"},
+}
+var eavBackendsLock sync.RWMutex
+
+// 註冊自定義的 backend， 需要自己建 value 表， 可以用 GetEavValueTableSql
+func RegisterEavBackend(backend EavBackend) {
+	eavBackendsLock.Lock()
+	defer eavBackendsLock.Unlock()
+	eavBackends[backend.Type] = backend
+}
+
+func GetEavBackend(backendType string) (EavBackend, bool) {
+	eavBackendsLock.RLock()
+	defer eavBackendsLock.RUnlock()
+	backend, ok := eavBackends[backendType]
+	return backend, ok
+}
+
+// 按名字排序
+func GetEavBackends() []EavBackend {
+	eavBackendsLock.RLock()
+	defer eavBackendsLock.RUnlock()
+	backends := make([]EavBackend, 0, len(eavBackends))
+	for _, backend := range eavBackends {
+		backends = append(backends, backend)
+	}
+	sort.Slice(backends, func(i, j int) bool { return backends[i].Type < backends[j].Type })
+	return backends
+}
+
+// eav 字段的 DbType， Field.DbType 優先， 否則用 backend 的 DbType
+func eavFieldDbType(field Field) string {
+	if field.DbType != "" || !field.IsEav {
+		return field.DbType
+	}
+	backend, _ := GetEavBackend(field.EavType)
+	return backend.DbType
+}
+
+// 建 value 表的 sql， 用於 migration， 例如 GetEavValueTableSql("user", "entity_id", "int")
+func GetEavValueTableSql(entityTable string, primaryField string, backendType string) (string, error) {
+	backend, ok := GetEavBackend(backendType)
+	if !ok {
+		return "", fmt.Errorf("unknown backend type %s", backendType)
+	}
+	table := entityTable + "_" + backend.Type
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n"+
+		"  `entity_id` bigint unsigned NOT NULL,\n"+
+		"  `locale` varchar(255),\n"+
+		"  `scope` varchar(64) not null default '',\n"+
+		"  `attribute_name` varchar(255),\n"+
+		"  `value`  %s,\n"+
+		"  `position` int not null default 0,\n"+
+		"  `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3),\n"+
+		"   CONSTRAINT %s_entity_id_%s_%s FOREIGN KEY (`entity_id`) REFERENCES `%s`(`%s`) ON DELETE CASCADE ON UPDATE CASCADE,\n"+
+		"   UNIQUE KEY %s_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`)\n"+
+		") ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;",
+		table, backend.SqlType, table, entityTable, primaryField, entityTable, primaryField, table), nil
+}
+
+// 過濾 eav 字段時把值轉換為 backend 的類型， 這樣 int, decimal 按數字比較
+// 只轉換比較、in 和 between 的值， like、regexp 等模式的值保持字符串
+func (e *Collection) convertFilterValue(field string, condition string, value interface{}) interface{} {
+	def, ok := e.Model.GetTableFields()[field]
+	if !ok || !def.IsEav || value == nil {
+		return value
+	}
+	operator := strings.ToLower(strings.TrimSpace(condition))
+	if _, ok := filterComparisonOperators[operator]; !ok {
+		switch operator {
+		case "in", "not in", "between", "nbetween", "not between":
+		default:
+			return value
+		}
+	}
+	resource := e.Model.GetResourceModel()
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Slice {
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, resource.Convert(field, v.Index(i).Interface()))
+		}
+		return values
+	}
+	return resource.Convert(field, value)
+}
@@ -0,0 +1,73 @@
+package core
+
+import "strings"
+
+// collection 用 AddFieldToSelect 時只 join 選擇、過濾和排序的 eav 字段
+// 其他 eav 字段在任一元素第一次 GetData 時為整個 collection 一次加載
+type lazyEavLoader struct {
+	elems  []Basictablemodelinterface
+	fields map[string]Field
+	loaded bool
+}
+
+func (l *lazyEavLoader) load(model Basictablemodelinterface) {
+	if l.loaded {
+		return
+	}
+	primaryField := model.GetPrimaryFieldName()
+	rows := make([]map[string]interface{}, 0, len(l.elems))
+	for _, elem := range l.elems {
+		rows = append(rows, map[string]interface{}{primaryField: elem.GetResourceModel().GetData(primaryField)})
+	}
+	loadPivotEavValues(model, rows, l.fields)
+	for index, elem := range l.elems {
+		resource := elem.GetResourceModel()
+		for key, value := range rows[index] {
+			if key != primaryField {
+				resource.SetOriginData(key, value).SetData(key, value)
+			}
+		}
+	}
+	l.loaded = true
+}
+
+// field 是未加載的 eav 字段或者它的 __locale_ 字段
+func (e *Basictablemodel) isLazyEavField(field string) bool {
+	if e.lazyEav == nil || e.lazyEav.loaded {
+		return false
+	}
+	_, ok := e.lazyEav.fields[strings.TrimPrefix(strings.ToLower(field), eavLocaleColumnPrefix)]
+	return ok
+}
+
+func (e *Basictablemodel) loadLazyEav(field string) {
+	if e.isLazyEavField(field) {
+		e._transaction(func() {
+			e.lazyEav.load(e)
+		})
+	}
+}
+
+// AddFieldToSelect 時需要 join 的 eav 字段和延遲加載的 eav 字段， 沒有 AddFieldToSelect 時全部 join
+func (e *Collection) splitEavFields(eavFields map[string]Field) (map[string]Field, map[string]Field) {
+	lazy := make(map[string]Field)
+	if len(e.ColumnsOfMaintable) == 0 {
+		return eavFields, lazy
+	}
+	if _, ok := e.Model.GetModel().(EavModelInterface); ok {
+		return eavFields, lazy
+	}
+	selected := make(map[string]bool)
+	for _, column := range e.ColumnsOfMaintable {
+		selected[strings.ToLower(column)] = true
+	}
+	joined := make(map[string]Field)
+	for key, field := range eavFields {
+		if selected[key] || e.UsedFields[key] {
+			joined[key] = field
+		} else {
+			lazy[key] = field
+		}
+	}
+	return joined, lazy
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 一條 union all 查詢加載實體所有 locale 的 eav 字段， 只包括 global 的值， 不包括帶 scope 的值
+// 返回 locale => field => value， 只包含有值的 locale， 多值字段是 []string
+func (e *Basictablemodel) LoadAllLocales() map[string]map[string]interface{} {
+	result := make(map[string]map[string]interface{})
+	e._transaction(func() {
+		id := e.GetData(e.GetPrimaryFieldName())
+		if id == nil {
+			panic(fmt.Errorf("entity is not loaded"))
+		}
+		eavFields := e.GetEavFields()
+		sqls := make([]string, 0)
+		values := make([]interface{}, 0)
+		for _, eavType := range eavFieldTypes(eavFields) {
+			sqls = append(sqls, "select locale, attribute_name, position, "+eavValueAsChar(eavType, "value")+" as value from "+e.Model.GetTableName()+"_"+eavType+" where entity_id = ? and scope = ''")
+			values = append(values, id)
+		}
+		if len(sqls) == 0 {
+			return
+		}
+		connection := e.GetConnection()
+		rows := connection.Fetch(connection.Expr("select * from ("+strings.Join(sqls, " union all ")+") as t order by locale, attribute_name, position", values...))
+		resource := e.GetResourceModel()
+		for _, row := range rows {
+			field := ConvertToString(row["attribute_name"])
+			def, ok := eavFields[field]
+			if !ok {
+				continue
+			}
+			locale := ConvertToString(row["locale"])
+			if _, ok := result[locale]; !ok {
+				result[locale] = make(map[string]interface{})
+			}
+			if def.IsMultiValue {
+				items, _ := result[locale][field].([]string)
+				result[locale][field] = append(items, ConvertToString(row["value"]))
+				continue
+			}
+			if row["value"] == nil {
+				result[locale][field] = nil
+				continue
+			}
+			result[locale][field] = resource.Convert(field, row["value"])
+		}
+	})
+	return result
+}
+
+// union all 不同 value 表時把 value 轉換為字符串， datetime 跟 ConvertToTimeString 的格式一樣
+func eavValueAsChar(eavType string, column string) string {
+	if backend, _ := GetEavBackend(eavType); strings.HasPrefix(backend.SqlType, "datetime") {
+		return "date_format(" + column + ", '%Y-%m-%d %H:%i:%s')"
+	}
+	return "cast(" + column + " as char)"
+}
+
+// 按名字排序的 eav type， 每個 type 一個 value 表
+func eavFieldTypes(eavFields map[string]Field) []string {
+	types := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, field := range eavFields {
+		if !seen[field.EavType] {
+			seen[field.EavType] = true
+			types = append(types, field.EavType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// 設置其他 locale (可以包括 scope， 見 ScopedLocale) 的值， 在下一次 Save 的事務中一起保存
+// locale 是當前保存的 locale 時同時 SetData
+func (e *Basictablemodel) SetDataForLocale(locale string, field string, value interface{}) Basictablemodelinterface {
+	field = strings.ToLower(field)
+	if locale == e.GetSaveLocale(field) {
+		return e.SetData(field, value)
+	}
+	if e.localeData == nil {
+		e.localeData = make(map[string]map[string]interface{})
+	}
+	if _, ok := e.localeData[locale]; !ok {
+		e.localeData[locale] = make(map[string]interface{})
+	}
+	e.localeData[locale][field] = value
+	return e
+}
+
+// 在 Save 的事務中執行， 每個 value 表一條 insert ... on duplicate key update
+func (e *Basictablemodel) saveLocaleData() {
+	if len(e.localeData) == 0 {
+		return
+	}
+	id := e.GetData(e.GetPrimaryFieldName())
+	if id == nil {
+		panic(fmt.Errorf("entity must be saved before saving other locales"))
+	}
+	eavFields := e.GetEavFields()
+	resource := e.GetResourceModel()
+	rows := make(map[string][]map[string]interface{})
+	locales := make([]string, 0, len(e.localeData))
+	for locale := range e.localeData {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		fields := make([]string, 0, len(e.localeData[locale]))
+		for field := range e.localeData[locale] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			def, ok := eavFields[field]
+			if !ok {
+				panic(fmt.Errorf("%s is not an eav field", field))
+			}
+			value := resource.Convert(field, e.localeData[locale][field])
+			if e.DeleteRedundantLocaleValues && e.ResourceModel.isRedundantLocaleValue(field, def, locale, value) {
+				e.ResourceModel.deleteLocaleValue(field, def, locale)
+				continue
+			}
+			if def.IsMultiValue {
+				e.ResourceModel.saveMultiValueField(field, def, locale, value)
+				continue
+			}
+			table := e.Model.GetTableName() + "_" + def.EavType
+			valueLocale, scope := splitScopedLocale(locale)
+			rows[table] = append(rows[table], map[string]interface{}{"value": value, "entity_id": id, "locale": valueLocale, "scope": scope, "attribute_name": field})
+		}
+	}
+	connection := e.GetConnection()
+	for _, table := range sortedKeys(rows) {
+		connection.InsertMultiOnUpdate(table, rows[table])
+	}
+}
+
+func sortedKeys(values map[string][]map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// 多值 eav 字段 (Field.IsMultiValue) 加載時用 group_concat 連接， 用這個字符分隔
+// InitDB 會提高 group_concat_max_len， 避免長列表被截斷
+const eavMultiValueSeparator = "\x1f"
+
+// 多值字段的值統一轉換為 []string， 字符串按 eavMultiValueSeparator 拆分
+func convertToStringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case string:
+		if v == "" {
+			return []string{}
+		}
+		return strings.Split(v, eavMultiValueSeparator)
+	case []byte:
+		return convertToStringSlice(string(v))
+	}
+	values := make([]string, 0)
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, ConvertToString(rv.Index(i).Interface()))
+		}
+		return values
+	}
+	return append(values, ConvertToString(value))
+}
+
+// 多值字段的 select 字段和 locale 字段， 按 locale chain 取第一個有值的 locale
+func (e *basictableResource) getMultiValueColumns(key string, field Field, chain []string) []string {
+	table := e.getEavTableByField(field.EavType)
+	values := make([]string, 0, len(chain))
+	for _, valueLocale := range chain {
+		values = append(values, fmt.Sprintf(`(select group_concat(v.value order by v.position separator '%s') from %s as v where v.entity_id = m.%s and %s and v.attribute_name = "%s")`,
+			eavMultiValueSeparator, table, e.Model.GetPrimaryFieldName(), scopedLocaleSql("v", valueLocale), key))
+	}
+	return []string{fmt.Sprintf("coalesce(%s) as %s", strings.Join(values, ", "), key), eavLocaleColumn(key, values, chain)}
+}
+
+// 多值字段每個值保存一行， position 從 0 開始， 先刪除舊的值
+func (e *basictableResource) saveMultiValueField(key string, field Field, locale string, value interface{}) {
+	table := e.getEavTableByField(field.EavType)
+	entityId := e.GetData(e.Model.GetPrimaryFieldName())
+	// 列表沒有變化時不重寫， 保留 updated_at， 見 GetTranslationReport
+	stored, _ := e.getStoredLocaleValue(key, field, locale).([]string)
+	if slices.Equal(stored, convertToStringSlice(value)) {
+		return
+	}
+	valueLocale, scope := splitScopedLocale(locale)
+	e.Connection.Delete(table, e.Connection.Expr("entity_id = ? and locale = ? and scope = ? and attribute_name = ?", entityId, valueLocale, scope, key))
+	if value == nil {
+		return
+	}
+	rows := make([]map[string]interface{}, 0)
+	for position, item := range convertToStringSlice(value) {
+		rows = append(rows, map[string]interface{}{"entity_id": entityId, "locale": valueLocale, "scope": scope, "attribute_name": key, "position": position, "value": item})
+	}
+	e.Connection.InsertMulti(table, rows)
+}
+
+// 多值字段的 "contains any" 和 "contains all"， 按加載後的值 (包括 default locale) 判斷
+func multiValueConditionSql(column string, condition string, value interface{}) (string, []interface{}, bool) {
+	join := ""
+	switch strings.ToLower(condition) {
+	case "contains any":
+		join = " or "
+	case "contains all":
+		join = " and "
+	default:
+		return "", nil, false
+	}
+	items := convertToStringSlice(value)
+	if len(items) == 0 {
+		return "(1 = 0)", []interface{}{}, true
+	}
+	sqls := make([]string, 0, len(items))
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		sqls = append(sqls, "locate(concat(char(31 using utf8mb4), ?, char(31 using utf8mb4)), concat(char(31 using utf8mb4), "+column+", char(31 using utf8mb4))) > 0")
+		values = append(values, item)
+	}
+	return "(" + strings.Join(sqls, join) + ")", values, true
+}
+
+// 多值字段用 multiValueConditionSql， 其他用 filterConditionSql
+func (e *Collection) fieldConditionSql(field string, column string, condition string, value interface{}) (string, []interface{}, error) {
+	def, ok := e.Model.GetTableFields()[field]
+	if err := checkMatchField(condition, field, ok && !def.IsEav && (column == field || column == "e."+field)); err != nil {
+		return "", nil, err
+	}
+	if ok && def.IsMultiValue {
+		if sql, values, ok := multiValueConditionSql(column, condition, value); ok {
+			return sql, values, nil
+		}
+		return filterConditionSql(column, condition, value)
+	}
+	return filterConditionSql(column, condition, e.convertFilterValue(field, condition, value))
+}
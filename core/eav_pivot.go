@@ -0,0 +1,134 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// eav 字段的加載方式
+const (
+	EavLoadStrategyJoin  = "join"  // 默認， 每個 eav 字段和 locale 一個 left join
+	EavLoadStrategyPivot = "pivot" // 先查主表， 再每個 value 表一條 in 查詢， 在 Go 中合併
+)
+
+// 返回 model 的 eav 加載方式
+func eavLoadStrategy(model Basictablemodelinterface) string {
+	if _, ok := model.GetModel().(EavModelInterface); ok {
+		return EavLoadStrategyJoin
+	}
+	if m, ok := model.GetModel().(EavLoadStrategyInterface); ok && m.GetEavLoadStrategy() != "" {
+		return m.GetEavLoadStrategy()
+	}
+	return EavLoadStrategyJoin
+}
+
+// pivot 方式加載 rows (主表的行) 的 eav 字段， 跟 GetEavAsTable 一樣按 locale chain 取第一個不為 null 的值
+func loadPivotEavValues(model Basictablemodelinterface, rows []map[string]interface{}, fields map[string]Field) {
+	if len(rows) == 0 || len(fields) == 0 {
+		return
+	}
+	primaryField := model.GetPrimaryFieldName()
+	ids := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row[primaryField])
+	}
+	chain := model.GetValueLocaleChain()
+	locales, scopes := make([]string, 0, len(chain)), make([]string, 0, len(chain))
+	for _, valueLocale := range chain {
+		locale, scope := splitScopedLocale(valueLocale)
+		locales, scopes = append(locales, locale), append(scopes, scope)
+	}
+	// entity id => field => ScopedLocale => values (按 position)， 不在 chain 中的組合不會用到
+	values := make(map[string]map[string]map[string][]interface{})
+	connection := model.GetConnection()
+	for _, eavType := range eavFieldTypes(fields) {
+		names := make([]string, 0)
+		for key, field := range fields {
+			if field.EavType == eavType {
+				names = append(names, key)
+			}
+		}
+		sort.Strings(names)
+		sql := "select entity_id, locale, scope, attribute_name, value from " + model.GetTableName() + "_" + eavType +
+			" where entity_id in (?) and locale in (?) and scope in (?) and attribute_name in (?) order by position"
+		for _, row := range connection.Fetch(connection.Expr(sql, ids, locales, scopes, names)) {
+			id := ConvertToString(row["entity_id"])
+			field := ConvertToString(row["attribute_name"])
+			locale := joinScopedLocale(ConvertToString(row["locale"]), ConvertToString(row["scope"]))
+			if _, ok := values[id]; !ok {
+				values[id] = make(map[string]map[string][]interface{})
+			}
+			if _, ok := values[id][field]; !ok {
+				values[id][field] = make(map[string][]interface{})
+			}
+			values[id][field][locale] = append(values[id][field][locale], row["value"])
+		}
+	}
+	for _, row := range rows {
+		entityValues := values[ConvertToString(row[primaryField])]
+		for key, field := range fields {
+			row[key] = nil
+			row[eavLocaleColumnPrefix+key] = nil
+			for _, locale := range chain {
+				value := pivotValue(entityValues[key][locale], field)
+				if value != nil {
+					row[key] = value
+					row[eavLocaleColumnPrefix+key] = locale
+					break
+				}
+			}
+		}
+	}
+}
+
+// 單值字段返回第一個值， 多值字段返回 []string， 沒有值時返回 nil
+func pivotValue(values []interface{}, field Field) interface{} {
+	if !field.IsMultiValue {
+		if len(values) == 0 {
+			return nil
+		}
+		return values[0]
+	}
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != nil {
+			items = append(items, ConvertToString(value))
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
+// 覆蓋 model 的加載方式
+func (e *Collection) SetEavLoadStrategy(strategy string) CollectionInterface {
+	e.EavLoadStrategy = strategy
+	return e
+}
+
+// 記錄過濾和排序用到的字段， eav 字段需要 join
+func (e *Collection) useField(field string) {
+	e.UsedFields[strings.ToLower(field)] = true
+}
+
+// pivot 時不能過濾和排序 eav 字段， 用到時改用 join
+func (e *Collection) isPivotLoad() bool {
+	strategy := e.EavLoadStrategy
+	if strategy == "" {
+		strategy = eavLoadStrategy(e.Model)
+	}
+	if strategy != EavLoadStrategyPivot {
+		return false
+	}
+	if _, ok := e.Model.GetModel().(EavModelInterface); ok {
+		return false
+	}
+	eavFields := e.Model.GetEavFields()
+	for field := range e.UsedFields {
+		if _, ok := eavFields[field]; ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 刪除字段在 locale (可以包括 scope) 的值
+func (e *basictableResource) deleteLocaleValue(key string, field Field, locale string) {
+	valueLocale, scope := splitScopedLocale(locale)
+	e.Connection.Delete(e.getEavTableByField(field.EavType), e.Connection.Expr("entity_id = ? and locale = ? and scope = ? and attribute_name = ?", e.GetData(e.Model.GetPrimaryFieldName()), valueLocale, scope, key))
+}
+
+// 數據庫中字段在 locale 的值， 沒有時返回 nil， 多值字段返回 []string
+func (e *basictableResource) getStoredLocaleValue(key string, field Field, locale string) interface{} {
+	valueLocale, scope := splitScopedLocale(locale)
+	rows := e.Connection.Fetch(e.Connection.Expr("select value from "+e.getEavTableByField(field.EavType)+" where entity_id = ? and locale = ? and scope = ? and attribute_name = ? order by position", e.GetData(e.Model.GetPrimaryFieldName()), valueLocale, scope, key))
+	if len(rows) == 0 {
+		return nil
+	}
+	if field.IsMultiValue {
+		values := make([]string, 0, len(rows))
+		for _, row := range rows {
+			values = append(values, ConvertToString(row["value"]))
+		}
+		return values
+	}
+	return e.Convert(key, rows[0]["value"])
+}
+
+// 值是 nil (多值字段是空的)， 或者不是 default locale 且跟 default locale 的值一樣
+func (e *basictableResource) isRedundantLocaleValue(key string, field Field, locale string, value interface{}) bool {
+	if value == nil || (field.IsMultiValue && len(convertToStringSlice(value)) == 0) {
+		return true
+	}
+	defaultLocale := e.Model.GetDefaultLocale()
+	if defaultLocale == "" || locale == defaultLocale {
+		return false
+	}
+	stored := e.getStoredLocaleValue(key, field, defaultLocale)
+	if stored == nil {
+		return false
+	}
+	if field.IsMultiValue {
+		return reflect.DeepEqual(convertToStringSlice(value), stored)
+	}
+	return ConvertToString(e.Convert(key, value)) == ConvertToString(stored)
+}
+
+// 保存時刪除多餘的 locale 值， 見 isRedundantLocaleValue
+func (e *Basictablemodel) SetDeleteRedundantLocaleValues(isDelete bool) Basictablemodelinterface {
+	e.DeleteRedundantLocaleValues = isDelete
+	return e
+}
+
+func (e *Basictablemodel) IsDeleteRedundantLocaleValues() bool {
+	return e.DeleteRedundantLocaleValues
+}
+
+// 刪除當前保存的 locale 的值， 字段回退到 fallback 的值， 跟 UseDefault 一樣
+func (e *Basictablemodel) UnsetLocaleValue(field string) Basictablemodelinterface {
+	return e.UseDefault(field)
+}
+
+// 維護用： 刪除 model 所有 value 表中 value 為 null 的行， 和跟 DefaultLocale 的值一樣的其他 locale 的行， 返回刪除的行數
+// 只跟 DefaultLocale 比較， 有 FallbackLocales 時刪除後可能取到中間 locale 的值
+// 帶 scope 的值 (見 ScopedLocale) 覆蓋同一個 locale 的值， 跟 DefaultLocale 一樣也不刪除， 只跟 global 的 DefaultLocale 比較
+func PurgeRedundantLocaleValues(model Basictablemodelinterface) (int64, error) {
+	var count int64
+	defaultLocale := model.GetDefaultLocale()
+	eavFields := model.GetEavFields()
+	err := transaction(model.GetConnectionName(), func(connection DBConnectionInterface) {
+		for _, eavType := range eavFieldTypes(eavFields) {
+			table := model.GetTableName() + "_" + eavType
+			single := make([]string, 0)
+			for key, field := range eavFields {
+				if field.EavType == eavType && !field.IsMultiValue {
+					single = append(single, key)
+				}
+			}
+			count += connection.Exec("delete from " + table + " where value is null")
+			if defaultLocale == "" || len(single) == 0 {
+				continue
+			}
+			// 多值字段要整個列表一樣， 不在這裡處理
+			count += connection.Exec("delete v from "+table+" as v inner join "+table+" as d on d.entity_id = v.entity_id and d.attribute_name = v.attribute_name and d.position = v.position and d.locale = ? and d.scope = ''"+
+				" where v.locale <> ? and v.scope = '' and binary v.value = binary d.value and v.attribute_name in ("+strings.TrimSuffix(strings.Repeat("?,", len(single)), ",")+")",
+				append([]interface{}{defaultLocale, defaultLocale}, stringsToInterfaces(single)...)...)
+		}
+		if count > 0 {
+			invalidateFlatIndexTable(connection, model.GetTableName())
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
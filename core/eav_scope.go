@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// eav 值的範圍， 例如 {Type: "store", Id: 3}， 零值是 global
+// 保存在 value 表的 scope 字段中， 例如 "store:3"， global 是空字符串
+type EavScope struct {
+	Type string
+	Id   uint64
+}
+
+func (s EavScope) IsGlobal() bool {
+	return s.Type == ""
+}
+
+func (s EavScope) String() string {
+	if s.IsGlobal() {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", s.Type, s.Id)
+}
+
+// locale 加上 scope， 例如 "en-US@store:3"， 用於 GetValueLocaleChain、GetDataLocale 和 SetDataForLocale
+// 保存時拆分為 value 表的 locale 和 scope 字段
+func ScopedLocale(locale string, scope EavScope) string {
+	return joinScopedLocale(locale, scope.String())
+}
+
+// ScopedLocale 的反向操作， 例如 GetDataLocale 的返回值
+func ParseScopedLocale(value string) (string, EavScope) {
+	locale, scope, ok := strings.Cut(value, "@")
+	if !ok {
+		return value, EavScope{}
+	}
+	scopeType, id, _ := strings.Cut(scope, ":")
+	return locale, EavScope{Type: scopeType, Id: ConvertToUint64(id)}
+}
+
+// value 表的 locale 和 scope 字段
+func splitScopedLocale(value string) (string, string) {
+	locale, scope := ParseScopedLocale(value)
+	return locale, scope.String()
+}
+
+// value 表的 locale 和 scope 字段組成 ScopedLocale
+func joinScopedLocale(locale string, scope string) string {
+	if scope == "" {
+		return locale
+	}
+	return locale + "@" + scope
+}
+
+// value 表中 ScopedLocale 的條件， 例如 v.locale = "en-US" and v.scope = "store:3"
+func scopedLocaleSql(alias string, value string) string {
+	locale, scope := splitScopedLocale(value)
+	return fmt.Sprintf(`%s.locale = "%s" and %s.scope = "%s"`, alias, locale, alias, scope)
+}
+
+var eavScopeParents = make(map[string]func(id uint64) EavScope)
+var eavScopeParentsLock sync.RWMutex
+
+// 註冊 scope 類型的上一級， 例如 store 的上一級是它所屬的 website， 返回零值時上一級是 global
+func RegisterEavScopeParent(scopeType string, parent func(id uint64) EavScope) {
+	eavScopeParentsLock.Lock()
+	defer eavScopeParentsLock.Unlock()
+	eavScopeParents[scopeType] = parent
+}
+
+// 按註冊的上一級展開 scope， 例如 store 3 => [store 3, website 1]， 不包括 global
+func ResolveEavScopes(scope EavScope) []EavScope {
+	scopes := make([]EavScope, 0)
+	seen := make(map[EavScope]bool)
+	for !scope.IsGlobal() && !seen[scope] {
+		seen[scope] = true
+		scopes = append(scopes, scope)
+		eavScopeParentsLock.RLock()
+		parent, ok := eavScopeParents[scope.Type]
+		eavScopeParentsLock.RUnlock()
+		if !ok {
+			break
+		}
+		scope = parent(scope.Id)
+	}
+	return scopes
+}
+
+// 加載時依次查找的 scope， 從最具體的開始， 例如 store 3, website 1， 最後是 global
+func (e *Basictablemodel) SetEavScopes(scopes ...EavScope) Basictablemodelinterface {
+	e.EavScopes = scopes
+	return e
+}
+
+// 用 ResolveEavScopes 展開 scope， 例如 store 3 => store 3, website 1, global
+func (e *Basictablemodel) SetEavScope(scope EavScope) Basictablemodelinterface {
+	return e.SetEavScopes(ResolveEavScopes(scope)...)
+}
+
+func (e *Basictablemodel) GetEavScopes() []EavScope {
+	return e.EavScopes
+}
+
+// 保存時寫入的 scope， 默認是 global
+func (e *Basictablemodel) SetSaveScope(scope EavScope) Basictablemodelinterface {
+	e.SaveScope = scope
+	return e
+}
+
+func (e *Basictablemodel) GetSaveScope() EavScope {
+	return e.SaveScope
+}
+
+// GetLocaleChain 的每個 locale 展開 scope， 例如 "de-AT@store:3", "de-AT@website:1", "de-AT", "de-DE@store:3" ...
+// 同一個 locale 內 scope 優先， 然後才 fallback 到下一個 locale
+func (e *Basictablemodel) GetValueLocaleChain() []string {
+	scopes := append(append([]EavScope{}, e.EavScopes...), EavScope{})
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, locale := range e.GetLocaleChain() {
+		for _, scope := range scopes {
+			value := ScopedLocale(locale, scope)
+			if !seen[value] {
+				seen[value] = true
+				chain = append(chain, value)
+			}
+		}
+	}
+	return chain
+}
+
+// 保存字段時寫入的 locale 字段， IsGlobal 的字段用 DefaultLocale
+func (e *Basictablemodel) GetSaveLocale(field string) string {
+	locale := e.GetLocale()
+	if def, ok := e.GetTableFields()[strings.ToLower(field)]; ok && def.IsGlobal && e.GetDefaultLocale() != "" {
+		locale = e.GetDefaultLocale()
+	}
+	return ScopedLocale(locale, e.SaveScope)
+}
+
+// 刪除當前 locale 和 SaveScope 的值， 字段重新按 scope 和 locale 的順序取值
+func (e *Basictablemodel) UseDefault(fields ...string) Basictablemodelinterface {
+	e._transaction(func() {
+		eavFields := e.GetEavFields()
+		for _, field := range fields {
+			field = strings.ToLower(field)
+			def, ok := eavFields[field]
+			if !ok {
+				panic(fmt.Errorf("%s is not an eav field", field))
+			}
+			e.ResourceModel.deleteLocaleValue(field, def, e.GetSaveLocale(field))
+		}
+		e.reloadEavFields(fields...)
+		e.reindexFlatEntity()
+	})
+	return e
+}
+
+// 從數據庫重新加載 eav 字段的值和 locale
+func (e *Basictablemodel) reloadEavFields(fields ...string) {
+	connection := e.GetConnection()
+	resource := e.GetResourceModel()
+	row := connection.FetchRow(connection.Expr("select * from "+resource.GetEavAsTable()+" as t where t."+e.GetPrimaryFieldName()+" = ?", e.GetData(e.GetPrimaryFieldName())))
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		for _, key := range []string{field, eavLocaleColumnPrefix + field} {
+			resource.SetOriginData(key, row[key])
+			resource.SetData(key, row[key])
+		}
+	}
+}
+
+// collection 和元素都用這些 scope
+func (e *Collection) SetEavScopes(scopes ...EavScope) CollectionInterface {
+	e.Model.SetEavScopes(scopes...)
+	return e
+}
+
+func (e *Collection) SetEavScope(scope EavScope) CollectionInterface {
+	e.Model.SetEavScope(scope)
+	return e
+}
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Collection.Filter 的條件樹， 例如 Or(FilterField("age").Gt(18), Not(FilterField("name").Like("jo%")))
+type FilterExpr interface {
+	toSql(e *Collection) (string, []interface{}, error)
+}
+
+type filterGroup struct {
+	join  string
+	exprs []FilterExpr
+}
+
+type filterNot struct {
+	expr FilterExpr
+}
+
+// 單個字段的條件， Operator 跟 AddFieldToFilter 的 key 一樣
+type FilterCondition struct {
+	Field    string
+	Operator string
+	Value    interface{}
+}
+
+// 沒有條件時為真
+func And(exprs ...FilterExpr) FilterExpr {
+	return &filterGroup{join: " and ", exprs: exprs}
+}
+
+// 沒有條件時為假
+func Or(exprs ...FilterExpr) FilterExpr {
+	return &filterGroup{join: " or ", exprs: exprs}
+}
+
+func Not(expr FilterExpr) FilterExpr {
+	return &filterNot{expr: expr}
+}
+
+func (f *filterGroup) toSql(e *Collection) (string, []interface{}, error) {
+	if len(f.exprs) == 0 {
+		if f.join == " or " {
+			return "(1 = 0)", []interface{}{}, nil
+		}
+		return "(1 = 1)", []interface{}{}, nil
+	}
+	sqls := make([]string, 0, len(f.exprs))
+	values := make([]interface{}, 0)
+	for _, expr := range f.exprs {
+		sql, exprValues, err := expr.toSql(e)
+		if err != nil {
+			return "", nil, err
+		}
+		sqls = append(sqls, sql)
+		values = append(values, exprValues...)
+	}
+	return "(" + strings.Join(sqls, f.join) + ")", values, nil
+}
+
+func (f *filterNot) toSql(e *Collection) (string, []interface{}, error) {
+	sql, values, err := f.expr.toSql(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(not " + sql + ")", values, nil
+}
+
+func (f *FilterCondition) toSql(e *Collection) (string, []interface{}, error) {
+	field := strings.ToLower(f.Field)
+	column, ok := e.filterColumn(field)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown filter field %s", f.Field)
+	}
+	e.useField(field)
+	return e.fieldConditionSql(field, column, f.Operator, f.Value)
+}
+
+// 字段條件的構造器， FilterField("age").Gt(18)
+type FieldFilter struct {
+	name string
+}
+
+func FilterField(name string) FieldFilter {
+	return FieldFilter{name: name}
+}
+
+func (f FieldFilter) Op(operator string, value interface{}) FilterExpr {
+	return &FilterCondition{Field: f.name, Operator: operator, Value: value}
+}
+
+func (f FieldFilter) Eq(value interface{}) FilterExpr {
+	return f.Op("=", value)
+}
+
+func (f FieldFilter) Neq(value interface{}) FilterExpr {
+	return f.Op("!=", value)
+}
+
+func (f FieldFilter) Gt(value interface{}) FilterExpr {
+	return f.Op(">", value)
+}
+
+func (f FieldFilter) Gte(value interface{}) FilterExpr {
+	return f.Op(">=", value)
+}
+
+func (f FieldFilter) Lt(value interface{}) FilterExpr {
+	return f.Op("<", value)
+}
+
+func (f FieldFilter) Lte(value interface{}) FilterExpr {
+	return f.Op("<=", value)
+}
+
+func (f FieldFilter) Like(value interface{}) FilterExpr {
+	return f.Op("like", value)
+}
+
+func (f FieldFilter) NotLike(value interface{}) FilterExpr {
+	return f.Op("not like", value)
+}
+
+func (f FieldFilter) Between(from interface{}, to interface{}) FilterExpr {
+	return f.Op("between", []interface{}{from, to})
+}
+
+func (f FieldFilter) NotBetween(from interface{}, to interface{}) FilterExpr {
+	return f.Op("nbetween", []interface{}{from, to})
+}
+
+// 值中的 % 和 _ 會轉義
+func (f FieldFilter) StartsWith(value string) FilterExpr {
+	return f.Op("starts", value)
+}
+
+func (f FieldFilter) EndsWith(value string) FilterExpr {
+	return f.Op("ends", value)
+}
+
+func (f FieldFilter) Contains(value string) FilterExpr {
+	return f.Op("contains", value)
+}
+
+func (f FieldFilter) Regexp(pattern string) FilterExpr {
+	return f.Op("regexp", pattern)
+}
+
+// 逗號分隔的字段中有 value
+func (f FieldFilter) FindInSet(value interface{}) FilterExpr {
+	return f.Op("finset", value)
+}
+
+// match ... against (natural language mode)， 只能用在主表的非 eav 字段， 需要 FULLTEXT 索引
+func (f FieldFilter) Match(query string) FilterExpr {
+	return f.Op("match", query)
+}
+
+func (f FieldFilter) In(values interface{}) FilterExpr {
+	return f.Op("in", values)
+}
+
+func (f FieldFilter) NotIn(values interface{}) FilterExpr {
+	return f.Op("not in", values)
+}
+
+func (f FieldFilter) IsNull() FilterExpr {
+	return f.Op("null", nil)
+}
+
+func (f FieldFilter) NotNull() FilterExpr {
+	return f.Op("not null", nil)
+}
+
+// 多值字段
+func (f FieldFilter) ContainsAny(values interface{}) FilterExpr {
+	return f.Op("contains any", values)
+}
+
+func (f FieldFilter) ContainsAll(values interface{}) FilterExpr {
+	return f.Op("contains all", values)
+}
+
+// 條件樹編譯成 where， 字段錯誤時在 Load 和 GetSize 時返回
+func (e *Collection) Filter(expr FilterExpr) CollectionInterface {
+	sql, values, err := expr.toSql(e)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	e.DbSelect.Where(e.Connection.Expr(sql, values...))
+	return e
+}
+
+// 字段在 where 中的寫法， CollectionFieldInterface 可以 join 其他表的字段
+func (e *Collection) filterColumn(field string) (string, bool) {
+	if model, ok := e.Model.GetModel().(CollectionFieldInterface); ok {
+		if column := model.AddJoinField(e, field); column != "" {
+			return column, true
+		}
+		if _, ok := e.Model.GetTableFields()[field]; ok {
+			return "e." + field, true
+		}
+		return "", false
+	}
+	if _, ok := e.Model.GetTableFields()[field]; ok {
+		return "e." + field, true
+	}
+	return "", false
+}
+
+// 允許的操作符， 其他操作符返回錯誤， 不會拼接到 sql 中
+var filterComparisonOperators = map[string]string{
+	"=":  "=",
+	"!=": "!=",
+	"<>": "!=",
+	">":  ">",
+	">=": ">=",
+	"<":  "<",
+	"<=": "<=",
+}
+
+// like 的轉義字符， 避免 sql 字符串中反斜杠的處理
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// 轉義 % 和 _， 只保留開頭和結尾的 %， 例如 "jo%" 和 "%100%_%"
+func escapeLikeValue(value string) string {
+	trimmed := strings.TrimLeft(value, "%")
+	inner := strings.TrimRight(trimmed, "%")
+	return value[:len(value)-len(trimmed)] + likeEscaper.Replace(inner) + trimmed[len(inner):]
+}
+
+// 單個條件的 sql， AddFieldToFilter、Filter 和 LoadOneByFilter 共用
+func filterConditionSql(field string, condition string, value interface{}) (string, []interface{}, error) {
+	operator := strings.ToLower(strings.TrimSpace(condition))
+	if symbol, ok := filterComparisonOperators[operator]; ok {
+		return "(" + field + " " + symbol + " ?)", []interface{}{value}, nil
+	}
+	switch operator {
+	case "in", "not in":
+		return "(" + field + " " + operator + " (?))", []interface{}{value}, nil
+	case "null", "not null":
+		return "(" + field + " is " + operator + ")", []interface{}{}, nil
+	case "like", "not like", "nlike":
+		not := map[string]string{"like": "", "not like": "not ", "nlike": "not "}[operator]
+		return "(" + field + " " + not + "like ? escape '!')", []interface{}{escapeLikeValue(ConvertToString(value))}, nil
+	case "starts":
+		return "(" + field + " like ? escape '!')", []interface{}{likeEscaper.Replace(ConvertToString(value)) + "%"}, nil
+	case "ends":
+		return "(" + field + " like ? escape '!')", []interface{}{"%" + likeEscaper.Replace(ConvertToString(value))}, nil
+	case "contains", "ncontains":
+		not := map[string]string{"contains": "", "ncontains": "not "}[operator]
+		return "(" + field + " " + not + "like ? escape '!')", []interface{}{"%" + likeEscaper.Replace(ConvertToString(value)) + "%"}, nil
+	case "between", "nbetween", "not between":
+		values := reflect.ValueOf(value)
+		if value == nil || values.Kind() != reflect.Slice || values.Len() != 2 {
+			return "", nil, fmt.Errorf("%s of %s needs two values", operator, field)
+		}
+		not := ""
+		if operator != "between" {
+			not = "not "
+		}
+		return "(" + field + " " + not + "between ? and ?)", []interface{}{values.Index(0).Interface(), values.Index(1).Interface()}, nil
+	case "regexp":
+		return "(" + field + " regexp ?)", []interface{}{value}, nil
+	case "finset":
+		return "(find_in_set(?, " + field + ") > 0)", []interface{}{value}, nil
+	case "match":
+		// 調用前用 checkMatchField 檢查字段
+		return "(match(" + field + ") against (? in natural language mode))", []interface{}{ConvertToString(value)}, nil
+	}
+	return "", nil, fmt.Errorf("unknown filter operator %s", condition)
+}
+
+// match 只能用在有 FULLTEXT 索引的主表字段， eav 字段和 join 的字段返回錯誤
+func checkMatchField(condition string, field string, isMainColumn bool) error {
+	if strings.ToLower(strings.TrimSpace(condition)) == "match" && !isMainColumn {
+		return fmt.Errorf("match needs a fulltext indexed main table column, %s is not", field)
+	}
+	return nil
+}
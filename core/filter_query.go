@@ -0,0 +1,276 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 查詢字符串中的操作符， 例如 filter[age][gte]=18
+var queryFilterOperators = map[string]string{
+	"eq":        "=",
+	"ne":        "!=",
+	"neq":       "!=",
+	"gt":        ">",
+	"gte":       ">=",
+	"lt":        "<",
+	"lte":       "<=",
+	"like":      "like",
+	"nlike":     "not like",
+	"in":        "in",
+	"nin":       "not in",
+	"null":      "null",
+	"nnull":     "not null",
+	"between":   "between",
+	"nbetween":  "nbetween",
+	"starts":    "starts",
+	"ends":      "ends",
+	"contains":  "contains",
+	"ncontains": "ncontains",
+	"regexp":    "regexp",
+	"finset":    "finset",
+	"match":     "match",
+}
+
+// 緊湊格式的操作符， 例如 age>=18,name~jo， 長的在前面
+var compactFilterOperators = []struct {
+	symbol   string
+	operator string
+}{
+	{">=", ">="},
+	{"<=", "<="},
+	{"!=", "!="},
+	{"!~", "ncontains"},
+	{"=", "="},
+	{">", ">"},
+	{"<", "<"},
+	{"~", "contains"},
+}
+
+var queryFilterKey = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// 可以過濾和排序的字段， 必須明確列出， 為空時不能過濾或者排序
+type QueryOptions struct {
+	Filterable  []string
+	Sortable    []string
+	MaxPageSize int // 大於 0 時限制 page[size]
+}
+
+type QueryOrder struct {
+	Field string
+	Dir   string
+}
+
+// ParseCollectionQuery 的結果， 用 Apply 添加到 collection
+type CollectionQuery struct {
+	Filter   FilterExpr
+	Orders   []QueryOrder
+	Page     int
+	PageSize int
+}
+
+func queryWhitelist(model Basictablemodelinterface, fields []string) map[string]bool {
+	whitelist := make(map[string]bool)
+	tableFields := model.GetTableFields()
+	for _, field := range fields {
+		if _, ok := tableFields[strings.ToLower(field)]; ok {
+			whitelist[strings.ToLower(field)] = true
+		}
+	}
+	return whitelist
+}
+
+// 解析 ?filter[age][gte]=18&filter[name][like]=jo%&sort=-created_at&page[size]=20&page[number]=2
+// filter=age>=18,name~jo 是緊湊格式， ~ 是包含， 多個條件之間是 and
+func ParseCollectionQuery(model Basictablemodelinterface, values url.Values, options QueryOptions) (*CollectionQuery, error) {
+	filterable := queryWhitelist(model, options.Filterable)
+	query := &CollectionQuery{Orders: make([]QueryOrder, 0)}
+	exprs := make([]FilterExpr, 0)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// 重複的 filter 之間是 and， sort 和 page 不能重複
+		if len(values[key]) > 1 && (key == "sort" || strings.HasPrefix(key, "page[")) {
+			return nil, fmt.Errorf("%s must not be repeated", key)
+		}
+		for _, value := range values[key] {
+			switch {
+			case key == "filter":
+				expr, err := ParseFilterString(model, value, options)
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, expr)
+			case strings.HasPrefix(key, "filter["):
+				match := queryFilterKey.FindStringSubmatch(key)
+				if match == nil {
+					return nil, fmt.Errorf("invalid filter %s", key)
+				}
+				field := strings.ToLower(match[1])
+				if !filterable[field] {
+					return nil, fmt.Errorf("field %s is not filterable", match[1])
+				}
+				name := match[2]
+				if name == "" {
+					name = "eq"
+				}
+				operator, ok := queryFilterOperators[strings.ToLower(name)]
+				if !ok {
+					return nil, fmt.Errorf("unknown filter operator %s", name)
+				}
+				expr, err := queryCondition(model, field, operator, value)
+				if err != nil {
+					return nil, err
+				}
+				exprs = append(exprs, expr)
+			case key == "sort":
+				orders, err := parseQuerySort(model, value, options)
+				if err != nil {
+					return nil, err
+				}
+				query.Orders = orders
+			case key == "page[size]" || key == "page[number]":
+				number, err := strconv.Atoi(value)
+				if err != nil || number < 1 {
+					return nil, fmt.Errorf("invalid %s %s", key, value)
+				}
+				if key == "page[size]" {
+					if options.MaxPageSize > 0 && number > options.MaxPageSize {
+						number = options.MaxPageSize
+					}
+					query.PageSize = number
+				} else {
+					query.Page = number
+				}
+			}
+		}
+	}
+	if len(exprs) > 0 {
+		query.Filter = And(exprs...)
+	}
+	return query, nil
+}
+
+// 緊湊格式， 例如 age>=18,name~jo， in 的值用 | 分隔， 例如 age=18|19
+func ParseFilterString(model Basictablemodelinterface, filter string, options QueryOptions) (FilterExpr, error) {
+	filterable := queryWhitelist(model, options.Filterable)
+	exprs := make([]FilterExpr, 0)
+	for _, part := range strings.Split(filter, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		expr, err := parseCompactCondition(model, part, filterable)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return And(exprs...), nil
+}
+
+func parseCompactCondition(model Basictablemodelinterface, part string, filterable map[string]bool) (FilterExpr, error) {
+	index := strings.IndexAny(part, "=!<>~")
+	if index <= 0 {
+		return nil, fmt.Errorf("invalid filter %s", part)
+	}
+	field := strings.ToLower(strings.TrimSpace(part[:index]))
+	if !filterable[field] {
+		return nil, fmt.Errorf("field %s is not filterable", field)
+	}
+	rest := part[index:]
+	for _, item := range compactFilterOperators {
+		if !strings.HasPrefix(rest, item.symbol) {
+			continue
+		}
+		value := strings.TrimSpace(rest[len(item.symbol):])
+		operator := item.operator
+		if strings.Contains(value, "|") && (operator == "=" || operator == "!=") {
+			value = strings.ReplaceAll(value, "|", ",")
+			operator = map[string]string{"=": "in", "!=": "not in"}[operator]
+		}
+		return queryCondition(model, field, operator, value)
+	}
+	return nil, fmt.Errorf("unknown filter operator in %s", part)
+}
+
+// 字符串值按字段類型轉換， in 和 between 的值用逗號分隔， between 必須是兩個值
+func queryCondition(model Basictablemodelinterface, field string, operator string, value string) (FilterExpr, error) {
+	switch operator {
+	case "in", "not in", "between", "nbetween":
+		items := make([]interface{}, 0)
+		for _, item := range strings.Split(value, ",") {
+			items = append(items, queryValue(model, field, item))
+		}
+		if (operator == "between" || operator == "nbetween") && len(items) != 2 {
+			return nil, fmt.Errorf("%s of %s needs two values", operator, field)
+		}
+		return FilterField(field).Op(operator, items), nil
+	case "null", "not null":
+		return FilterField(field).Op(operator, nil), nil
+	}
+	return FilterField(field).Op(operator, queryValue(model, field, value)), nil
+}
+
+func queryValue(model Basictablemodelinterface, field string, value string) interface{} {
+	if def, ok := model.GetTableFields()[field]; ok && def.DbType == "bool" {
+		return ConvertToBool(value)
+	}
+	return value
+}
+
+// sort=-created_at,name， - 是倒序
+func parseQuerySort(model Basictablemodelinterface, value string, options QueryOptions) ([]QueryOrder, error) {
+	sortable := queryWhitelist(model, options.Sortable)
+	orders := make([]QueryOrder, 0)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		dir := "asc"
+		if strings.HasPrefix(field, "-") {
+			dir = "desc"
+			field = field[1:]
+		}
+		if !sortable[field] {
+			return nil, fmt.Errorf("field %s is not sortable", field)
+		}
+		orders = append(orders, QueryOrder{Field: field, Dir: dir})
+	}
+	return orders, nil
+}
+
+// 添加過濾、排序和分頁
+func (q *CollectionQuery) Apply(collection CollectionInterface) CollectionInterface {
+	if q.Filter != nil {
+		collection.Filter(q.Filter)
+	}
+	for _, order := range q.Orders {
+		collection.AddOrder(order.Field, order.Dir)
+	}
+	if q.PageSize > 0 {
+		collection.SetPageSize(q.PageSize)
+	}
+	if q.Page > 0 {
+		collection.SetPage(q.Page)
+	}
+	return collection
+}
+
+// 解析並添加到 collection， 錯誤時 collection 不變
+func (e *Collection) ApplyQuery(values url.Values, options QueryOptions) error {
+	query, err := ParseCollectionQuery(e.Model, values, options)
+	if err != nil {
+		return err
+	}
+	query.Apply(e)
+	return nil
+}
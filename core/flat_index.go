@@ -0,0 +1,201 @@
+package core
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// flat 表的狀態保存在 eav_flat_index， signature 是字段的摘要， 字段修改後 flat 表過期
+var flatTableNameReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// 例如 user 和 en-US 的 flat 表是 user_flat_en_us
+func FlatTableName(table string, locale string) string {
+	return table + "_flat_" + strings.Trim(flatTableNameReplacer.ReplaceAllString(strings.ToLower(locale), "_"), "_")
+}
+
+func (e *Basictablemodel) isFlatIndexed() bool {
+	if m, ok := interface{}(e.Model).(IsFlatIndexedInterface); ok {
+		return m.IsFlatIndexed()
+	}
+	return false
+}
+
+// 字段定義的摘要， 包括主表字段和 eav 字段
+func flatSignature(model Basictablemodelinterface) string {
+	eavFields := model.GetEavFields()
+	items := make([]string, 0)
+	for key, field := range model.GetTableFields() {
+		if field.IsEav {
+			if _, ok := eavFields[key]; !ok {
+				continue
+			}
+		}
+		items = append(items, fmt.Sprintf("%s:%t:%s:%t:%t", strings.ToLower(key), field.IsEav, field.EavType, field.IsMultiValue, field.IsGlobal))
+	}
+	sort.Strings(items)
+	sum := md5.Sum([]byte(strings.Join(items, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// flat 表只保存 Locale 和 DefaultLocale 的值， 有 FallbackLocales, EavScopes 或者 attribute set 時不能用
+func flatIndexable(model Basictablemodelinterface) bool {
+	if _, ok := model.GetModel().(EavModelInterface); ok {
+		return false
+	}
+	set, err := model.GetAttributeSet()
+	return model.GetLocale() != "" && len(model.GetFallbackLocales()) == 0 && len(model.GetEavScopes()) == 0 && set == nil && err == nil
+}
+
+// 全量重建 locales 的 flat 表， 先寫入臨時表再替換， DDL 不能在事務中執行
+// 重建時不加鎖： 在 create ... select 之後、 rename 之前提交的 Save 只更新舊表， 新表中是舊的值
+// 所以要在沒有寫入的時候重建， 或者重建後用 InvalidateFlatIndex 和 ReindexFlat 再重建一次
+func ReindexFlat(model Basictablemodelinterface, locales ...string) error {
+	return recoverError(func() {
+		factory := selfFactory(model)
+		connection := newConnection(model.GetConnectionName())
+		primaryField := model.GetPrimaryFieldName()
+		for _, locale := range locales {
+			localeModel := factory(locale, model.GetDefaultLocale())
+			if !flatIndexable(localeModel) {
+				panic(fmt.Errorf("model %s cannot be flat indexed", model.GetTableName()))
+			}
+			flat := FlatTableName(model.GetTableName(), locale)
+			connection.Exec("drop table if exists " + flat + "_tmp")
+			connection.Exec("create table " + flat + "_tmp as select * from " + localeModel.GetResourceModel().GetEavAsTable() + " as e")
+			connection.Exec("alter table " + flat + "_tmp add primary key (" + primaryField + ")")
+			if tableExists(connection, flat) {
+				connection.Exec("rename table " + flat + " to " + flat + "_old, " + flat + "_tmp to " + flat)
+				connection.Exec("drop table " + flat + "_old")
+			} else {
+				connection.Exec("rename table " + flat + "_tmp to " + flat)
+			}
+			clearFlatColumnsCache(model.GetConnectionName(), flat)
+			connection.InsertMultiOnUpdate("eav_flat_index", []map[string]interface{}{{
+				"entity_type":    model.GetTableName(),
+				"locale":         locale,
+				"default_locale": model.GetDefaultLocale(),
+				"signature":      flatSignature(localeModel),
+				"is_valid":       1,
+			}})
+		}
+	})
+}
+
+func tableExists(connection DBConnectionInterface, table string) bool {
+	return ConvertToInt(connection.FetchOne(connection.Expr("select count(*) from information_schema.tables where table_schema = database() and table_name = ?", table))) > 0
+}
+
+// flat 表的字段， 每次 Save 都要用， 按 connection 和表名緩存， ReindexFlat 重建時清空
+var flatColumnsCache = make(map[string][]string)
+var flatColumnsCacheLock sync.RWMutex
+
+func flatColumns(connection DBConnectionInterface, connectionName string, flat string) []string {
+	key := connectionName + "|" + flat
+	flatColumnsCacheLock.RLock()
+	columns, ok := flatColumnsCache[key]
+	flatColumnsCacheLock.RUnlock()
+	if ok {
+		return columns
+	}
+	columns = make([]string, 0)
+	for _, row := range connection.Fetch(connection.Expr("select column_name as name from information_schema.columns where table_schema = database() and table_name = ? order by ordinal_position", flat)) {
+		columns = append(columns, "`"+ConvertToString(row["name"])+"`")
+	}
+	flatColumnsCacheLock.Lock()
+	flatColumnsCache[key] = columns
+	flatColumnsCacheLock.Unlock()
+	return columns
+}
+
+func clearFlatColumnsCache(connectionName string, flat string) {
+	flatColumnsCacheLock.Lock()
+	defer flatColumnsCacheLock.Unlock()
+	delete(flatColumnsCache, connectionName+"|"+flat)
+}
+
+// 標記 model 所有 locale 的 flat 表過期， 批量修改 value 表後調用， 需要 ReindexFlat 重建
+func InvalidateFlatIndex(model Basictablemodelinterface) error {
+	return recoverError(func() {
+		invalidateFlatIndexTable(model.GetConnection(), model.GetTableName())
+	})
+}
+
+func invalidateFlatIndexTable(connection DBConnectionInterface, table string) {
+	connection.Exec("update eav_flat_index set is_valid = 0 where entity_type = ?", table)
+}
+
+func (e *Basictablemodel) invalidateFlatIndex() {
+	if e.isFlatIndexed() {
+		invalidateFlatIndexTable(e.GetConnection(), e.Model.GetTableName())
+	}
+}
+
+type flatIndexState struct {
+	Locale        string
+	DefaultLocale string
+	Signature     string
+}
+
+// 有效的 flat 表
+func (e *Basictablemodel) getValidFlatIndexes() []flatIndexState {
+	connection := e.GetConnection()
+	states := make([]flatIndexState, 0)
+	for _, row := range connection.Fetch(connection.Expr("select locale, default_locale, signature from eav_flat_index where entity_type = ? and is_valid = 1 order by locale", e.Model.GetTableName())) {
+		states = append(states, flatIndexState{Locale: ConvertToString(row["locale"]), DefaultLocale: ConvertToString(row["default_locale"]), Signature: ConvertToString(row["signature"])})
+	}
+	return states
+}
+
+// collection 用的 flat 表， 沒有或者過期時返回空字符串
+func (e *Basictablemodel) getFreshFlatTable() string {
+	if !e.isFlatIndexed() || !flatIndexable(e) {
+		return ""
+	}
+	connection := e.GetConnection()
+	row := connection.FetchRow(connection.Expr("select default_locale, signature from eav_flat_index where entity_type = ? and locale = ? and is_valid = 1", e.Model.GetTableName(), e.GetLocale()))
+	if row == nil || ConvertToString(row["default_locale"]) != e.GetDefaultLocale() || ConvertToString(row["signature"]) != flatSignature(e) {
+		return ""
+	}
+	return FlatTableName(e.Model.GetTableName(), e.GetLocale())
+}
+
+// 在 Save 的事務中執行， 更新實體在所有有效 flat 表中的行， 字段修改過時標記過期
+// 其他 locale 的值也會影響 fallback， 所以每個 locale 都更新
+func (e *Basictablemodel) reindexFlatEntity() {
+	if !e.isFlatIndexed() {
+		return
+	}
+	id := e.GetData(e.GetPrimaryFieldName())
+	if id == nil {
+		return
+	}
+	connection := e.GetConnection()
+	factory := selfFactory(e)
+	primaryField := e.GetPrimaryFieldName()
+	for _, state := range e.getValidFlatIndexes() {
+		localeModel := factory(state.Locale, state.DefaultLocale)
+		flat := FlatTableName(e.Model.GetTableName(), state.Locale)
+		if flatSignature(localeModel) != state.Signature {
+			connection.Exec("update eav_flat_index set is_valid = 0 where entity_type = ? and locale = ?", e.Model.GetTableName(), state.Locale)
+			continue
+		}
+		columns := flatColumns(connection, e.GetConnectionName(), flat)
+		connection.Exec("delete from "+flat+" where "+primaryField+" = ?", id)
+		connection.Exec("insert into "+flat+" ("+strings.Join(columns, ",")+") select "+strings.Join(columns, ",")+" from "+localeModel.GetResourceModel().GetEavAsTable()+" as e where e."+primaryField+" = ?", id)
+	}
+}
+
+// 在 Delete 的事務中執行
+func (e *Basictablemodel) deleteFlatEntity(id interface{}) {
+	if !e.isFlatIndexed() || id == nil {
+		return
+	}
+	for _, state := range e.getValidFlatIndexes() {
+		e.GetConnection().Exec("delete from "+FlatTableName(e.Model.GetTableName(), state.Locale)+" where "+e.GetPrimaryFieldName()+" = ?", id)
+	}
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// GetEavAsTable 為每個 eav 字段多加載一個 __locale_<field> 字段， 保存值實際來自的 locale
+const eavLocaleColumnPrefix = "__locale_"
+
+// 加載 eav 字段時依次查找的 locale: Locale, FallbackLocales, DefaultLocale， 去掉空的和重複的
+func (e *Basictablemodel) GetLocaleChain() []string {
+	chain := make([]string, 0, len(e.FallbackLocales)+2)
+	seen := make(map[string]bool)
+	for _, locale := range append(append([]string{e.GetLocale()}, e.FallbackLocales...), e.GetDefaultLocale()) {
+		if locale == "" || seen[locale] {
+			continue
+		}
+		seen[locale] = true
+		chain = append(chain, locale)
+	}
+	return chain
+}
+
+// 例如 Locale 是 de-AT， SetFallbackLocales("de-DE")， DefaultLocale 是 en-US
+func (e *Basictablemodel) SetFallbackLocales(locales ...string) Basictablemodelinterface {
+	e.FallbackLocales = locales
+	return e
+}
+
+func (e *Basictablemodel) GetFallbackLocales() []string {
+	return e.FallbackLocales
+}
+
+// eav 字段的值來自哪個 locale (有 scope 時包括 scope， 見 ParseScopedLocale)， 沒有值時返回空字符串， SetData 修改後返回保存時用的 locale
+func (e *Basictablemodel) GetDataLocale(field string) string {
+	field = strings.ToLower(field)
+	def, ok := e.GetTableFields()[field]
+	if !ok || !def.IsEav {
+		return ""
+	}
+	e.loadLazyEav(field)
+	resource := e.GetResourceModel()
+	if !reflect.DeepEqual(resource.GetData(field), resource.GetOriginData(field)) {
+		if resource.GetData(field) == nil {
+			return ""
+		}
+		return e.GetSaveLocale(field)
+	}
+	return ConvertToString(resource.GetData(eavLocaleColumnPrefix + field))
+}
+
+// collection 和元素都用這個 fallback
+func (e *Collection) SetFallbackLocales(locales ...string) CollectionInterface {
+	e.Model.SetFallbackLocales(locales...)
+	return e
+}
+
+// 第一個 locale 的別名是 e_<field>， default locale 是 e_<field>_default， 中間的是 e_<field>_<index>
+func eavLocaleAlias(key string, index int, locale string, defaultLocale string) string {
+	if index == 0 {
+		return "e_" + key
+	}
+	if locale == defaultLocale {
+		return "e_" + key + "_default"
+	}
+	return fmt.Sprintf("e_%s_%d", key, index)
+}
+
+// 值來自哪個 locale 的 select 字段， values 跟 chain 一一對應
+func eavLocaleColumn(key string, values []string, chain []string) string {
+	cases := make([]string, 0, len(chain))
+	for index, locale := range chain {
+		cases = append(cases, fmt.Sprintf(`when %s is not null then "%s"`, values[index], locale))
+	}
+	return fmt.Sprintf("case %s end as %s%s", strings.Join(cases, " "), eavLocaleColumnPrefix, key)
+}
@@ -30,6 +30,10 @@ type BasicModelBeforeSaveInterface interface {
 type BasicModelSaveInterface interface {
 	AfterSave(Basictablemodelinterface)
 }
+type BasicModelRelationsInterface interface {
+	GetRelations() map[string]Relation
+}
+
 type CollectionFieldInterface interface {
 	AddJoinField(collection CollectionInterface, field string) string
 }
@@ -38,6 +42,31 @@ type IsInnerTableEavInterface interface {
 	IsInnerTableEav() bool
 }
 
+// 合併 eav_attribute 表中的 eav 字段
+type IsRuntimeEavInterface interface {
+	IsRuntimeEav() bool
+}
+
+// 按 attribute_set_id 只加載和保存 attribute set 中的 eav 字段
+type IsAttributeSetEavInterface interface {
+	IsAttributeSetEav() bool
+}
+
+// 樹形 model， 需要 parent_id, path, level, position 字段
+type IsTreeInterface interface {
+	IsTree() bool
+}
+
+// 維護每個 locale 的 flat 表 (ReindexFlat)， collection 在 flat 表有效時不 join value 表
+type IsFlatIndexedInterface interface {
+	IsFlatIndexed() bool
+}
+
+// EavLoadStrategyJoin 或者 EavLoadStrategyPivot， 字段很多時 pivot 的 sql 比較小
+type EavLoadStrategyInterface interface {
+	GetEavLoadStrategy() string
+}
+
 func ModelFactory(callback func() Basictablemodelinterface) Basictablemodelinterface {
 	tableModel := callback()
 	tableModel.Init()
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 命名的 collection 條件， 用 Collection.Scope 應用， 例如 "active"： 啟用並且 30 天內創建的用戶
+type BasicModelScopesInterface interface {
+	GetScopes() map[string]func(CollectionInterface, ...interface{})
+}
+
+// 自動應用的 scope 名稱 (不帶參數)， 例如租戶或者軟刪除
+// Collection 的 Load, GetSize 和 model 的 LoadByField, LoadByFields, LoadOneByFilter 會應用， 用 WithoutDefaultScopes 去掉
+type BasicModelDefaultScopesInterface interface {
+	GetDefaultScopes() []string
+}
+
+// WithoutDefaultScopes 去掉的默認 scope， 沒有名稱時去掉全部
+type removedScopes struct {
+	all   bool
+	names map[string]bool
+}
+
+func (r *removedScopes) remove(names ...string) {
+	if len(names) == 0 {
+		r.all = true
+		return
+	}
+	if r.names == nil {
+		r.names = make(map[string]bool)
+	}
+	for _, name := range names {
+		r.names[name] = true
+	}
+}
+
+func (r *removedScopes) isRemoved(name string) bool {
+	return r.all || r.names[name]
+}
+
+func modelScope(model Basictablemodelinterface, name string) (func(CollectionInterface, ...interface{}), error) {
+	if m, ok := model.GetModel().(BasicModelScopesInterface); ok {
+		if scope, ok := m.GetScopes()[name]; ok {
+			return scope, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown scope %s", name)
+}
+
+// 沒有去掉的默認 scope
+func activeDefaultScopes(model Basictablemodelinterface, removed removedScopes) []string {
+	names := make([]string, 0)
+	if m, ok := model.GetModel().(BasicModelDefaultScopesInterface); ok {
+		for _, name := range m.GetDefaultScopes() {
+			if !removed.isRemoved(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// 應用 model 的命名 scope， 未知的 scope 在 Load 和 GetSize 時返回錯誤
+func (e *Collection) Scope(name string, args ...interface{}) CollectionInterface {
+	scope, err := modelScope(e.Model, name)
+	if err != nil {
+		e.BuildError = err
+		return e
+	}
+	scope(e, args...)
+	return e
+}
+
+// 不應用這些默認 scope， 沒有參數時不應用任何默認 scope
+func (e *Collection) WithoutDefaultScopes(names ...string) CollectionInterface {
+	e.removedScopes.remove(names...)
+	return e
+}
+
+// Load 前應用一次
+func (e *Collection) applyDefaultScopes() {
+	if e.defaultScopesApplied {
+		return
+	}
+	e.defaultScopesApplied = true
+	for _, name := range activeDefaultScopes(e.Model, e.removedScopes) {
+		e.Scope(name)
+	}
+}
+
+// 加載時不應用這些默認 scope， 沒有參數時不應用任何默認 scope
+func (e *Basictablemodel) WithoutDefaultScopes(names ...string) Basictablemodelinterface {
+	e.removedScopes.remove(names...)
+	return e
+}
+
+// 有默認 scope 時用 collection 查找， scope 的條件和 filters 在同一條查詢中， 最多返回 limit 行
+// 第二個返回值是 false 時沒有默認 scope， 由 ResourceModel 查找
+func (e *Basictablemodel) loadWithDefaultScopes(filters map[string]map[string]interface{}, limit int) ([]map[string]interface{}, bool) {
+	if len(activeDefaultScopes(e, e.removedScopes)) == 0 {
+		return nil, false
+	}
+	collection := newRelationCollection(e, selfFactory(e))
+	if c, ok := collection.(*Collection); ok {
+		c.removedScopes = e.removedScopes
+	}
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	// 每個字段單獨添加， 字段之間是 and
+	for _, field := range fields {
+		collection.AddFieldToFilter(map[string]map[string]interface{}{field: filters[field]})
+	}
+	collection.SetPageSize(limit)
+	elems := collection.GetElems()
+	if err := collection.GetLastError(); err != nil {
+		panic(err)
+	}
+	rows := make([]map[string]interface{}, 0, len(elems))
+	for _, elem := range elems {
+		if resource, ok := elem.GetResourceModel().(*basictableResource); ok {
+			rows = append(rows, resource.Data)
+		}
+	}
+	return rows, true
+}
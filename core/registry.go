@@ -0,0 +1,38 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// entity type => model factory， 多態關聯用 entity type 作為 discriminator
+var modelRegistry = make(map[string]func(locale string, defaultLocale string) Basictablemodelinterface)
+var modelRegistryTypes = make(map[reflect.Type]string)
+var modelRegistryLock sync.RWMutex
+
+// model 只用來確定類型， 例如 RegisterModel("user", &User{}, GetUserFactory)， 註冊時不會調用 factory
+func RegisterModel(entityType string, model BasicModelInterface, factory func(locale string, defaultLocale string) Basictablemodelinterface) error {
+	if entityType == "" || model == nil || factory == nil {
+		return fmt.Errorf("model %s needs a type and a factory", entityType)
+	}
+	modelRegistryLock.Lock()
+	defer modelRegistryLock.Unlock()
+	modelRegistry[entityType] = factory
+	modelRegistryTypes[reflect.TypeOf(model)] = entityType
+	return nil
+}
+
+func GetRegisteredModelFactory(entityType string) (func(locale string, defaultLocale string) Basictablemodelinterface, bool) {
+	modelRegistryLock.RLock()
+	defer modelRegistryLock.RUnlock()
+	factory, ok := modelRegistry[entityType]
+	return factory, ok
+}
+
+// 返回 model 註冊的 entity type， 沒有註冊時返回空字符串
+func GetEntityType(model Basictablemodelinterface) string {
+	modelRegistryLock.RLock()
+	defer modelRegistryLock.RUnlock()
+	return modelRegistryTypes[reflect.TypeOf(model.GetModel())]
+}
@@ -0,0 +1,359 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	RelationBelongsTo  = "belongs_to"
+	RelationHasMany    = "has_many"
+	RelationManyToMany = "many_to_many"
+	RelationMorphTo    = "morph_to"
+	RelationMorphMany  = "morph_many"
+)
+
+// Type: RelationBelongsTo / RelationHasMany / RelationManyToMany / RelationMorphTo / RelationMorphMany
+// Factory: 關聯 model 的 factory， 會傳入當前 model 的 locale 和 default locale， 為空時關聯本身的 model (例如 parent / children)
+// ForeignKey: belongs_to 時是本表的字段， has_many 時是關聯表的字段
+// LocalKey: 本表的字段， 默認是本表的 primary key
+// OwnerKey: 關聯表的字段， 默認是關聯表的 primary key
+// PivotTable: many_to_many 的中間表
+// PivotLocalKey: 中間表指向本表的字段
+// PivotForeignKey: 中間表指向關聯表的字段
+// MorphType: 多態關聯保存 entity type 的字段， morph_to 時是本表的字段， morph_many 時是關聯表的字段， entity type 見 RegisterModel
+type Relation struct {
+	Type            string
+	Factory         func(locale string, defaultLocale string) Basictablemodelinterface
+	ForeignKey      string
+	LocalKey        string
+	OwnerKey        string
+	PivotTable      string
+	PivotLocalKey   string
+	PivotForeignKey string
+	MorphType       string
+	Self            bool // 關聯本身的 model， 例如樹形的 parent, children， 不需要 Factory
+}
+
+func (r Relation) getLocalKey(owner Basictablemodelinterface) string {
+	if r.LocalKey != "" {
+		return strings.ToLower(r.LocalKey)
+	}
+	return owner.GetPrimaryFieldName()
+}
+
+func (r Relation) getOwnerKey(related Basictablemodelinterface) string {
+	if r.OwnerKey != "" {
+		return strings.ToLower(r.OwnerKey)
+	}
+	return related.GetPrimaryFieldName()
+}
+
+// 取出 models 中某字段不重複且非空的值
+func collectRelationKeys(models []Basictablemodelinterface, field string) []string {
+	keys := make([]string, 0)
+	exists := make(map[string]bool)
+	for _, model := range models {
+		value := model.GetData(field)
+		if value == nil {
+			continue
+		}
+		key := ConvertToString(value)
+		if key == "" || exists[key] {
+			continue
+		}
+		exists[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Self 的關聯用 owner 同一類型的 model， 其他關聯需要 Factory (morph_to 用註冊的 factory)
+func relationFactory(owner Basictablemodelinterface, relation Relation) func(locale string, defaultLocale string) Basictablemodelinterface {
+	if relation.Factory != nil {
+		return relation.Factory
+	}
+	if relation.Self {
+		return selfFactory(owner)
+	}
+	panic(fmt.Errorf("relation of %s has no factory", owner.GetModel().GetTableName()))
+}
+
+// owner 同一類型的 model， 優先用註冊的 factory
+func selfFactory(owner Basictablemodelinterface) func(locale string, defaultLocale string) Basictablemodelinterface {
+	if factory, ok := GetRegisteredModelFactory(GetEntityType(owner)); ok {
+		return factory
+	}
+	modelType := reflect.TypeOf(owner.GetModel())
+	return func(locale string, defaultLocale string) Basictablemodelinterface {
+		return ModelFactory(func() Basictablemodelinterface {
+			model := reflect.New(modelType.Elem()).Interface().(BasicModelInterface)
+			return &Basictablemodel{Model: model, Connection: owner.GetConnectionName(), Locale: locale, DefaultLocale: defaultLocale}
+		})
+	}
+}
+
+func newRelationCollection(owner Basictablemodelinterface, factory func(locale string, defaultLocale string) Basictablemodelinterface) CollectionInterface {
+	collection := CollectionFactory(func() Basictablemodelinterface {
+		return factory(owner.GetLocale(), owner.GetDefaultLocale())
+	})
+	// 跟 owner 在同一個事務中
+	db := owner.GetConnection().GetDb()
+	collection.GetConnection().SetDb(db)
+	if c, ok := collection.(*Collection); ok {
+		c.Model.GetConnection().SetDb(db)
+	}
+	collection.SetFallbackLocales(owner.GetFallbackLocales()...).SetEavScopes(owner.GetEavScopes()...)
+	return collection
+}
+
+// 加載失敗時 panic， 由調用者的 _transaction 設置 LastError， 不會變成空的關聯
+func relationElems(collection CollectionInterface) []Basictablemodelinterface {
+	elems := collection.GetElems()
+	if err := collection.GetLastError(); err != nil {
+		panic(err)
+	}
+	return elems
+}
+
+// 批量加載 models 的關聯， 每個關聯一條 in 查詢 (many_to_many 多一條中間表查詢， morph_to 每個 entity type 一條)
+// name 可以用 "." 加載嵌套關聯， 例如 "orders.items"
+func loadRelation(models []Basictablemodelinterface, name string) {
+	if len(models) == 0 {
+		return
+	}
+	name, nested, hasNested := strings.Cut(name, ".")
+	owner := models[0]
+	relation, ok := owner.GetRelations()[name]
+	if !ok {
+		panic(fmt.Errorf("unknown relation %s", name))
+	}
+	if relation.Factory == nil && !relation.Self && relation.Type != RelationMorphTo {
+		panic(fmt.Errorf("relation %s has no factory", name))
+	}
+
+	groups := make(map[string][]Basictablemodelinterface)
+	// 每個 model 在 groups 中的 key
+	groupKey := func(model Basictablemodelinterface) string {
+		return ConvertToString(model.GetData(relation.getLocalKey(owner)))
+	}
+	related := make([]Basictablemodelinterface, 0)
+	switch relation.Type {
+	case RelationBelongsTo:
+		foreignKey := strings.ToLower(relation.ForeignKey)
+		groupKey = func(model Basictablemodelinterface) string {
+			return ConvertToString(model.GetData(foreignKey))
+		}
+		keys := collectRelationKeys(models, foreignKey)
+		if len(keys) > 0 {
+			collection := newRelationCollection(owner, relationFactory(owner, relation))
+			ownerKey := relation.getOwnerKey(collection.Create())
+			collection.AddFieldToFilter(map[string]map[string]interface{}{ownerKey: {"in": keys}})
+			related = relationElems(collection)
+			for _, elem := range related {
+				key := ConvertToString(elem.GetData(ownerKey))
+				groups[key] = append(groups[key], elem)
+			}
+		}
+
+	case RelationHasMany, RelationMorphMany:
+		keys := collectRelationKeys(models, relation.getLocalKey(owner))
+		if len(keys) > 0 {
+			foreignKey := strings.ToLower(relation.ForeignKey)
+			collection := newRelationCollection(owner, relationFactory(owner, relation))
+			collection.AddFieldToFilter(map[string]map[string]interface{}{foreignKey: {"in": keys}})
+			if relation.Type == RelationMorphMany {
+				entityType := GetEntityType(owner)
+				if entityType == "" {
+					panic(fmt.Errorf("model %s is not registered", owner.GetModel().GetTableName()))
+				}
+				collection.AddFieldToFilter(map[string]map[string]interface{}{strings.ToLower(relation.MorphType): {"=": entityType}})
+			}
+			related = relationElems(collection)
+			for _, elem := range related {
+				key := ConvertToString(elem.GetData(foreignKey))
+				groups[key] = append(groups[key], elem)
+			}
+		}
+
+	case RelationManyToMany:
+		keys := collectRelationKeys(models, relation.getLocalKey(owner))
+		if len(keys) > 0 {
+			connection := owner.GetConnection()
+			sql := connection.Expr("select "+relation.PivotLocalKey+" as local_key, "+relation.PivotForeignKey+" as foreign_key from "+relation.PivotTable+" where "+relation.PivotLocalKey+" in (?)", keys)
+			pivots := connection.Fetch(sql)
+			foreignKeys := make([]string, 0)
+			for _, pivot := range pivots {
+				foreignKeys = append(foreignKeys, ConvertToString(pivot["foreign_key"]))
+			}
+			if len(foreignKeys) > 0 {
+				collection := newRelationCollection(owner, relationFactory(owner, relation))
+				ownerKey := relation.getOwnerKey(collection.Create())
+				collection.AddFieldToFilter(map[string]map[string]interface{}{ownerKey: {"in": foreignKeys}})
+				related = relationElems(collection)
+				relatedByKey := make(map[string]Basictablemodelinterface)
+				for _, elem := range related {
+					relatedByKey[ConvertToString(elem.GetData(ownerKey))] = elem
+				}
+				for _, pivot := range pivots {
+					if elem, ok := relatedByKey[ConvertToString(pivot["foreign_key"])]; ok {
+						key := ConvertToString(pivot["local_key"])
+						groups[key] = append(groups[key], elem)
+					}
+				}
+			}
+		}
+
+	case RelationMorphTo:
+		morphType := strings.ToLower(relation.MorphType)
+		foreignKey := strings.ToLower(relation.ForeignKey)
+		groupKey = func(model Basictablemodelinterface) string {
+			return ConvertToString(model.GetData(morphType)) + ":" + ConvertToString(model.GetData(foreignKey))
+		}
+		// 按 entity type 分組， 每個 entity type 一條 in 查詢
+		modelsByType := make(map[string][]Basictablemodelinterface)
+		entityTypes := make([]string, 0)
+		for _, model := range models {
+			entityType := ConvertToString(model.GetData(morphType))
+			if entityType == "" {
+				continue
+			}
+			if _, ok := modelsByType[entityType]; !ok {
+				entityTypes = append(entityTypes, entityType)
+			}
+			modelsByType[entityType] = append(modelsByType[entityType], model)
+		}
+		for _, entityType := range entityTypes {
+			factory, ok := GetRegisteredModelFactory(entityType)
+			if !ok {
+				panic(fmt.Errorf("unknown entity type %s", entityType))
+			}
+			keys := collectRelationKeys(modelsByType[entityType], foreignKey)
+			if len(keys) == 0 {
+				continue
+			}
+			collection := newRelationCollection(owner, factory)
+			ownerKey := relation.getOwnerKey(collection.Create())
+			collection.AddFieldToFilter(map[string]map[string]interface{}{ownerKey: {"in": keys}})
+			for _, elem := range relationElems(collection) {
+				key := entityType + ":" + ConvertToString(elem.GetData(ownerKey))
+				groups[key] = append(groups[key], elem)
+				related = append(related, elem)
+			}
+		}
+
+	default:
+		panic(fmt.Errorf("unknown relation type %s", relation.Type))
+	}
+
+	for _, model := range models {
+		elems, ok := groups[groupKey(model)]
+		if !ok {
+			elems = make([]Basictablemodelinterface, 0)
+		}
+		model.SetRelated(name, elems)
+	}
+	if hasNested {
+		if relation.Type == RelationMorphTo {
+			panic(fmt.Errorf("cannot load nested relation %s of morph_to relation %s", nested, name))
+		}
+		loadRelation(related, nested)
+	}
+}
+
+// 關聯的子查詢， 跟 collection 的 e 表關聯， eav 字段用 collection 的 locale
+func relationSubSelect(collection *Collection, name string, filters map[string]map[string]interface{}) (DBSelectInterface, error) {
+	owner := collection.Model
+	relation, ok := owner.GetRelations()[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown relation %s", name)
+	}
+	if relation.Type == RelationMorphTo {
+		return nil, fmt.Errorf("cannot filter by morph_to relation %s", name)
+	}
+	if relation.Factory == nil && !relation.Self {
+		return nil, fmt.Errorf("relation %s has no factory", name)
+	}
+	related := relationFactory(owner, relation)(owner.GetLocale(), owner.GetDefaultLocale())
+	alias := "r_" + name
+	table := related.GetTableName()
+	if len(related.GetEavFields()) > 0 {
+		table = related.GetResourceModel().GetEavAsTable()
+	}
+
+	dbselect := &DBSelect{}
+	dbselect.Init()
+	dbselect.From(table, alias, nil)
+	switch relation.Type {
+	case RelationBelongsTo:
+		dbselect.Where(alias + "." + relation.getOwnerKey(related) + " = e." + strings.ToLower(relation.ForeignKey))
+	case RelationHasMany:
+		dbselect.Where(alias + "." + strings.ToLower(relation.ForeignKey) + " = e." + relation.getLocalKey(owner))
+	case RelationMorphMany:
+		entityType := GetEntityType(owner)
+		if entityType == "" {
+			return nil, fmt.Errorf("model %s is not registered", owner.GetModel().GetTableName())
+		}
+		dbselect.Where(alias + "." + strings.ToLower(relation.ForeignKey) + " = e." + relation.getLocalKey(owner))
+		dbselect.Where(collection.GetConnection().Expr(alias+"."+strings.ToLower(relation.MorphType)+" = ?", entityType))
+	case RelationManyToMany:
+		pivotAlias := "p_" + name
+		dbselect.InnerJoin(pivotAlias, relation.PivotTable, pivotAlias+"."+relation.PivotForeignKey+" = "+alias+"."+relation.getOwnerKey(related), nil)
+		dbselect.Where(pivotAlias + "." + relation.PivotLocalKey + " = e." + relation.getLocalKey(owner))
+	default:
+		return nil, fmt.Errorf("unknown relation type %s", relation.Type)
+	}
+
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		if _, ok := related.GetTableFields()[strings.ToLower(field)]; !ok {
+			return nil, fmt.Errorf("unknown field %s of relation %s", field, name)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	connection := collection.GetConnection()
+	for _, field := range fields {
+		operators := make([]string, 0, len(filters[field]))
+		for operator := range filters[field] {
+			operators = append(operators, operator)
+		}
+		sort.Strings(operators)
+		def := related.GetTableFields()[strings.ToLower(field)]
+		for _, operator := range operators {
+			if err := checkMatchField(operator, field, !def.IsEav); err != nil {
+				return nil, err
+			}
+			conditionSql, conditionValues, err := filterConditionSql(alias+"."+strings.ToLower(field), operator, filters[field][operator])
+			if err != nil {
+				return nil, err
+			}
+			dbselect.Where(connection.Expr(conditionSql, conditionValues...))
+		}
+	}
+	// 關聯 model 的默認 scope， 用它的 collection 組裝主鍵的子查詢
+	if len(activeDefaultScopes(related, removedScopes{})) > 0 {
+		scoped, ok := newRelationCollection(owner, relationFactory(owner, relation)).(*Collection)
+		if !ok {
+			return nil, fmt.Errorf("relation %s has no collection", name)
+		}
+		scoped.applyDefaultScopes()
+		if scoped.BuildError != nil {
+			return nil, scoped.BuildError
+		}
+		eavFields := make(map[string]Field)
+		for key, field := range related.GetEavFields() {
+			if scoped.UsedFields[key] {
+				eavFields[key] = field
+			}
+		}
+		primaryField := related.GetPrimaryFieldName()
+		table, _ := scoped.fromTable(eavFields)
+		scoped.DbSelect.From(table, "e", map[string]string{primaryField: primaryField})
+		scopeSql, _ := scoped.DbSelect.Assemble()
+		dbselect.Where(alias + "." + primaryField + " in (" + scopeSql + ")")
+	}
+	return dbselect, nil
+}
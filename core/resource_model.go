@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,9 +18,13 @@ type BasictableResourceInterface interface {
 	Reset() BasictableResourceInterface
 	Save() BasictableResourceInterface
 	LoadByField(field string, value interface{}) BasictableResourceInterface
+	LoadByFields(values map[string]interface{}) BasictableResourceInterface
+	LoadOneByFilter(filters map[string]map[string]interface{}) BasictableResourceInterface
 	Delete() BasictableResourceInterface
 	GetConnection() DBConnectionInterface
 	GetEavAsTable() string
+	GetEavAsTableForFields(eavFields map[string]Field) string
+	Convert(field string, value interface{}) interface{}
 }
 
 type basictableResource struct {
@@ -44,7 +49,10 @@ func (e *basictableResource) Convert(field string, value interface{}) interface{
 	}
 	fields := e.Model.GetTableFields()
 	if def, ok := fields[field]; ok {
-		switch def.DbType {
+		if def.IsMultiValue {
+			return convertToStringSlice(value)
+		}
+		switch eavFieldDbType(def) {
 		case "int":
 			handleValue = ConvertToInt(value)
 
@@ -109,7 +117,7 @@ func (e *basictableResource) SetData(field string, value interface{}) Basictable
 	if modelField, ok := e.GetFieldDefByName(field); ok {
 		// 通过反射设置结构体变量的值
 		// time.Time  // TODO: aa
-		switch modelField.DbType {
+		switch eavFieldDbType(*modelField) {
 		case "time.Time":
 			t := ConvertToTime(handleValue)
 			updateField(e.Model.GetModel(), modelField.Name, t)
@@ -235,7 +243,17 @@ func (e *basictableResource) saveEavFields() BasictableResourceInterface {
 		value, ok := data[key]
 		if ok {
 			table := e.getEavTableByField(field.EavType)
-			eavData := []map[string]interface{}{{"value": value, "entity_id": e.GetData(e.Model.GetPrimaryFieldName()), "locale": locale, "attribute_name": key}}
+			valueLocale := e.Model.GetSaveLocale(key)
+			if e.Model.IsDeleteRedundantLocaleValues() && e.isRedundantLocaleValue(key, field, valueLocale, value) {
+				e.deleteLocaleValue(key, field, valueLocale)
+				continue
+			}
+			if field.IsMultiValue {
+				e.saveMultiValueField(key, field, valueLocale, value)
+				continue
+			}
+			locale, scope := splitScopedLocale(valueLocale)
+			eavData := []map[string]interface{}{{"value": value, "entity_id": e.GetData(e.Model.GetPrimaryFieldName()), "locale": locale, "scope": scope, "attribute_name": key}}
 			e.Connection.InsertMultiOnUpdate(table, eavData)
 		}
 	}
@@ -280,10 +298,25 @@ func (e *basictableResource) Save() BasictableResourceInterface {
 }
 
 func (e *basictableResource) LoadByField(field string, value interface{}) BasictableResourceInterface {
+	// 先查詢 attribute set， 只 join attribute set 的字段
+	if m, ok := e.Model.GetModel().(IsAttributeSetEavInterface); ok && m.IsAttributeSetEav() {
+		if def, ok := e.GetFieldDefByName(field); ok && !def.IsEav {
+			e.Reset()
+			e.SetData(AttributeSetField, e.Connection.FetchOne(e.Connection.Expr("select "+AttributeSetField+" from "+e.Model.GetTableName()+" where "+field+"=?", value)))
+		}
+	}
 	eavFields := e.Model.GetEavFields()
 	sql := ""
 	if len(eavFields) == 0 {
 		sql = e.Connection.Expr("select * from "+e.Model.GetTableName()+" as t where "+field+"=?", value)
+	} else if def, ok := e.GetFieldDefByName(field); ok && !def.IsEav && eavLoadStrategy(e.Model) == EavLoadStrategyPivot {
+		// 按主表字段查找時先查主表， 再按 locale chain 查 value 表
+		data := e.Connection.FetchRow(e.Connection.Expr("select * from "+e.Model.GetTableName()+" as t where "+field+"=?", value))
+		if data != nil {
+			loadPivotEavValues(e.Model, []map[string]interface{}{data}, eavFields)
+		}
+		e.LoadDbData(data)
+		return e
 	} else {
 		//sql := fmt.Sprintf("select m.* from %s as m ", e.Model.GetTableName())
 		sql = e.Connection.Expr("select * from "+e.GetEavAsTable()+"as t where "+field+"=?", value)
@@ -294,6 +327,92 @@ func (e *basictableResource) LoadByField(field string, value interface{}) Basict
 	return e
 }
 
+func (e *basictableResource) LoadByFields(values map[string]interface{}) BasictableResourceInterface {
+	return e.LoadOneByFilter(fieldsToFilters(values))
+}
+
+// LoadByFields 的值轉換為 LoadOneByFilter 的條件， nil 是 null
+func fieldsToFilters(values map[string]interface{}) map[string]map[string]interface{} {
+	filters := make(map[string]map[string]interface{})
+	for field, value := range values {
+		if value == nil {
+			filters[field] = map[string]interface{}{"null": nil}
+		} else {
+			filters[field] = map[string]interface{}{"=": value}
+		}
+	}
+	return filters
+}
+
+// 條件格式跟 Collection.AddFieldToFilter 一樣，多個字段之間是 and
+// 找不到 panic ErrNotFound， 多於一條 panic ErrMultipleResults
+func (e *basictableResource) LoadOneByFilter(filters map[string]map[string]interface{}) BasictableResourceInterface {
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		if _, ok := e.GetFieldDefByName(field); !ok {
+			panic(fmt.Errorf("unknown field %s", field))
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	conditions := make([]string, 0)
+	values := make([]interface{}, 0)
+	for _, field := range fields {
+		operators := make([]string, 0, len(filters[field]))
+		for operator := range filters[field] {
+			operators = append(operators, operator)
+		}
+		sort.Strings(operators)
+		def, _ := e.GetFieldDefByName(field)
+		for _, operator := range operators {
+			if err := checkMatchField(operator, field, !def.IsEav); err != nil {
+				panic(err)
+			}
+			conditionSql, conditionValues, err := filterConditionSql("t."+strings.ToLower(field), operator, filters[field][operator])
+			if err != nil {
+				panic(err)
+			}
+			conditions = append(conditions, conditionSql)
+			values = append(values, conditionValues...)
+		}
+	}
+	where := "1=1"
+	if len(conditions) > 0 {
+		where = e.Connection.Expr(strings.Join(conditions, " and "), values...)
+	}
+
+	// 跟 LoadByField 一樣先查詢 attribute set， 只 join attribute set 的字段
+	if m, ok := e.Model.GetModel().(IsAttributeSetEavInterface); ok && m.IsAttributeSetEav() {
+		mainFields := true
+		for _, field := range fields {
+			if def, _ := e.GetFieldDefByName(field); def.IsEav {
+				mainFields = false
+			}
+		}
+		if mainFields {
+			e.Reset()
+			e.SetData(AttributeSetField, e.Connection.FetchOne("select "+AttributeSetField+" from "+e.Model.GetTableName()+" as t where "+where+" limit 1"))
+		}
+	}
+	table := e.Model.GetTableName()
+	if len(e.Model.GetEavFields()) > 0 {
+		table = e.GetEavAsTable()
+	}
+	rows := e.Connection.Fetch("select * from " + table + " as t where " + where + " limit 2")
+	switch len(rows) {
+	case 0:
+		e.LoadDbData(nil)
+		panic(ErrNotFound)
+	case 1:
+		e.LoadDbData(rows[0])
+	default:
+		e.LoadDbData(nil)
+		panic(ErrMultipleResults)
+	}
+	return e
+}
+
 func (e *basictableResource) Delete() BasictableResourceInterface {
 	table := e.Model.GetTableName()
 	primaryValue := e.GetData(e.Model.GetPrimaryFieldName())
@@ -323,22 +442,40 @@ func (e *basictableResource) GetConnection() DBConnectionInterface {
 	return e.Connection
 }
 func (e *basictableResource) GetEavAsTable() string {
-	eavFields := e.Model.GetEavFields()
+	return e.GetEavAsTableForFields(e.Model.GetEavFields())
+}
+
+// 只 join eavFields 的 value 表， collection 用來只 join 選擇、過濾和排序的字段
+func (e *basictableResource) GetEavAsTableForFields(eavFields map[string]Field) string {
 	sql := ""
 	locale := e.Model.GetLocale()
 	defalutLocale := e.Model.GetDefaultLocale()
 	if eavModel, ok := e.Model.GetModel().(EavModelInterface); ok {
 		return eavModel.GetEavAsTable(locale, defalutLocale)
 	}
+	chain := e.Model.GetValueLocaleChain()
+	if len(chain) == 0 {
+		chain = []string{locale}
+	}
 	columns := make([]string, 0)
 	for key, field := range eavFields {
-		sql += fmt.Sprintf(`
-			left join %s as e_%s_default on e_%s_default.entity_id = m.%s and e_%s_default.locale="%s" and e_%s_default.attribute_name = "%s"
-			`, e.getEavTableByField(field.EavType), key, key, e.Model.GetPrimaryFieldName(), key, defalutLocale, key, key) +
-			fmt.Sprintf(`
-			left join %s as e_%s on e_%s.entity_id = m.%s and e_%s.locale="%s" and e_%s.attribute_name = "%s"
-			`, e.getEavTableByField(field.EavType), key, key, e.Model.GetPrimaryFieldName(), key, locale, key, key)
-		columns = append(columns, fmt.Sprintf("ifNUll(e_%s.value,e_%s_default.value) as %s", key, key, key))
+		if field.IsMultiValue {
+			columns = append(columns, e.getMultiValueColumns(key, field, chain)...)
+			continue
+		}
+		// 按 locale chain 每個 locale join 一次， 取第一個不為 null 的值
+		values := make([]string, 0, len(chain))
+		for index, valueLocale := range chain {
+			alias := eavLocaleAlias(key, index, valueLocale, defalutLocale)
+			sql += fmt.Sprintf(`
+			left join %s as %s on %s.entity_id = m.%s and %s and %s.attribute_name = "%s"
+			`, e.getEavTableByField(field.EavType), alias, alias, e.Model.GetPrimaryFieldName(), scopedLocaleSql(alias, valueLocale), alias, key)
+			values = append(values, alias+".value")
+		}
+		columns = append(columns, fmt.Sprintf("coalesce(%s) as %s", strings.Join(values, ","), key), eavLocaleColumn(key, values, chain))
+	}
+	if len(columns) == 0 {
+		return fmt.Sprintf("(select m.* from %s as m )", e.Model.GetTableName())
 	}
 	sql = fmt.Sprintf("(select m.*,%s from %s as m %s )", strings.Join(columns, ","), e.Model.GetTableName(), sql)
 	return sql
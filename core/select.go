@@ -16,6 +16,9 @@ type DBSelectInterface interface {
 	Init() DBSelectInterface
 	Reset() DBSelectInterface
 	Order(string) DBSelectInterface
+	Columns(...string) DBSelectInterface
+	Group(string) DBSelectInterface
+	Having(string) DBSelectInterface
 }
 
 type DBSelectError struct {
@@ -33,6 +36,10 @@ type DBSelect struct {
 	_limit  int
 	_offset int
 	_order  []string
+	// 原生的 column 表達式， 例如 count(*)， 設置後替代 from table 的 columns
+	_columns []string
+	_group   []string
+	_having  []string
 }
 
 func (this *DBSelect) From(table string, tableAlias string, columns map[string]string) DBSelectInterface {
@@ -43,6 +50,25 @@ func (this *DBSelect) From(table string, tableAlias string, columns map[string]s
 	this._from = from
 	return this
 }
+
+// 複製 select， 修改副本不影響原來的 select
+func (this *DBSelect) clone() *DBSelect {
+	clone := *this
+	if this._from != nil {
+		clone._from = make(map[string]interface{}, len(this._from))
+		for key, value := range this._from {
+			clone._from[key] = value
+		}
+	}
+	clone._join = append([]map[string]interface{}(nil), this._join...)
+	clone._where = append([]string(nil), this._where...)
+	clone._order = append([]string(nil), this._order...)
+	clone._columns = append([]string(nil), this._columns...)
+	clone._group = append([]string(nil), this._group...)
+	clone._having = append([]string(nil), this._having...)
+	return &clone
+}
+
 func (this *DBSelect) hasJoin(tableAlias string) bool {
 	for _, join := range this._join {
 		if join["tableAlias"] == tableAlias {
@@ -104,7 +130,9 @@ func (this *DBSelect) Assemble() (string, error) {
 
 	selectStr := "select "
 
-	if this._from["columns"] == nil {
+	if len(this._columns) > 0 {
+		selectStr += strings.Join(this._columns, ", ") + " "
+	} else if this._from["columns"] == nil {
 		if tableAlias != "" {
 			selectStr += tableAlias + ".* "
 		} else {
@@ -116,15 +144,15 @@ func (this *DBSelect) Assemble() (string, error) {
 			for alias, field := range columns {
 				if alias == field {
 					if tableAlias != "" {
-						selectStr += tableAlias + field + ", "
+						selectStr += tableAlias + "." + field + ", "
 					} else {
-						selectStr += table + field + ", "
+						selectStr += table + "." + field + ", "
 					}
 				} else {
 					if tableAlias != "" {
-						selectStr += tableAlias + field + " as " + alias + ", "
+						selectStr += tableAlias + "." + field + " as " + alias + ", "
 					} else {
-						selectStr += table + field + " as " + alias + ", "
+						selectStr += table + "." + field + " as " + alias + ", "
 					}
 				}
 			}
@@ -152,7 +180,7 @@ func (this *DBSelect) Assemble() (string, error) {
 					} else {
 						selectStr += joinTable + "." + field
 					}
-					if alias == field {
+					if alias != field {
 						selectStr += " as " + alias
 					}
 					selectStr += ", "
@@ -203,6 +231,14 @@ func (this *DBSelect) Assemble() (string, error) {
 		}
 		selectStr = strings.TrimSuffix(selectStr, "and ")
 	}
+	// 處理group by
+	if len(this._group) > 0 {
+		selectStr += " group by " + strings.Join(this._group, ", ")
+	}
+	// 處理having
+	if len(this._having) > 0 {
+		selectStr += " having " + strings.Join(this._having, " and ")
+	}
 	// 處理order by
 	if len(this._order) > 0 {
 		selectStr += " order by "
@@ -234,6 +270,9 @@ func (this *DBSelect) Reset() DBSelectInterface {
 	this._limit = 0
 	this._offset = 0
 	this._order = make([]string, 0)
+	this._columns = make([]string, 0)
+	this._group = make([]string, 0)
+	this._having = make([]string, 0)
 
 	return this
 }
@@ -241,3 +280,15 @@ func (this *DBSelect) Order(order string) DBSelectInterface {
 	this._order = append(this._order, order)
 	return this
 }
+func (this *DBSelect) Columns(columns ...string) DBSelectInterface {
+	this._columns = append(this._columns, columns...)
+	return this
+}
+func (this *DBSelect) Group(group string) DBSelectInterface {
+	this._group = append(this._group, group)
+	return this
+}
+func (this *DBSelect) Having(condition string) DBSelectInterface {
+	this._having = append(this._having, condition)
+	return this
+}
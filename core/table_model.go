@@ -8,18 +8,29 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrNotFound        = errors.New("entity not found")
+	ErrMultipleResults = errors.New("multiple entities found")
+)
+
 type Field struct {
-	Name       string
-	IsEav      bool
-	DbType     string
-	Autocreate bool
-	Autoupdate bool
-	EavType    string
+	Name         string
+	IsEav        bool
+	DbType       string
+	Autocreate   bool
+	Autoupdate   bool
+	EavType      string
+	Input        string // InputSelect / InputMultiselect， 值是 option id， label 用 GetDataLabel
+	IsRequired   bool   // 保存時不能為空
+	IsGlobal     bool   // eav 字段所有 locale 共用， 保存在 default locale
+	IsMultiValue bool   // eav 字段保存多個值， 值是 []string， value 表需要 position 字段
 }
 type Basictablemodelinterface interface {
 	Save() Basictablemodelinterface
 	Delete() Basictablemodelinterface
 	LoadByField(string, interface{}) Basictablemodelinterface
+	LoadByFields(map[string]interface{}) Basictablemodelinterface
+	LoadOneByFilter(map[string]map[string]interface{}) Basictablemodelinterface
 	LoadById(id interface{}) Basictablemodelinterface
 	GetTableName() string
 	GetTableFields() map[string]Field
@@ -36,14 +47,51 @@ type Basictablemodelinterface interface {
 	GetDefaultLocale() string
 	GetLastError() error
 	GetDeleteFields() []string
+	GetRelations() map[string]Relation
+	GetRelated(string) []Basictablemodelinterface
+	SetRelated(string, []Basictablemodelinterface) Basictablemodelinterface
+	LoadRelation(...string) Basictablemodelinterface
+	MoveTo(parent Basictablemodelinterface, position int) Basictablemodelinterface
+	GetChildren() CollectionInterface
+	GetDescendants() CollectionInterface
+	GetAncestors() CollectionInterface
+	GetAttributeSet() (*AttributeSet, error)
+	GetDataLabel(field string) string
+	GetLocaleChain() []string
+	SetFallbackLocales(...string) Basictablemodelinterface
+	GetFallbackLocales() []string
+	GetDataLocale(field string) string
+	SetEavScopes(...EavScope) Basictablemodelinterface
+	SetEavScope(EavScope) Basictablemodelinterface
+	GetEavScopes() []EavScope
+	SetSaveScope(EavScope) Basictablemodelinterface
+	GetSaveScope() EavScope
+	GetValueLocaleChain() []string
+	GetSaveLocale(field string) string
+	UseDefault(fields ...string) Basictablemodelinterface
+	LoadAllLocales() map[string]map[string]interface{}
+	SetDataForLocale(locale string, field string, value interface{}) Basictablemodelinterface
+	SetDeleteRedundantLocaleValues(bool) Basictablemodelinterface
+	IsDeleteRedundantLocaleValues() bool
+	UnsetLocaleValue(field string) Basictablemodelinterface
+	SaveForLocale(locale string, values map[string]interface{}) Basictablemodelinterface
+	WithoutDefaultScopes(names ...string) Basictablemodelinterface
 }
 type Basictablemodel struct {
-	ResourceModel *basictableResource
-	Model         BasicModelInterface
-	Connection    string
-	Locale        string
-	DefaultLocale string
-	LastError     error
+	ResourceModel               *basictableResource
+	Model                       BasicModelInterface
+	Connection                  string
+	Locale                      string
+	DefaultLocale               string
+	FallbackLocales             []string   // Locale 沒有值時依次查找， 最後是 DefaultLocale
+	EavScopes                   []EavScope // 例如 store, website， 最後是 global
+	SaveScope                   EavScope
+	DeleteRedundantLocaleValues bool                              // 保存時值為 nil 或者跟 DefaultLocale 一樣時刪除 locale 的行
+	localeData                  map[string]map[string]interface{} // SetDataForLocale 的值， Save 時保存
+	lazyEav                     *lazyEavLoader                    // collection 沒有 join 的 eav 字段
+	removedScopes               removedScopes                     // WithoutDefaultScopes
+	LastError                   error
+	Related                     map[string][]Basictablemodelinterface
 }
 
 func (e *Basictablemodel) GetLastError() error {
@@ -100,23 +148,58 @@ func (e *Basictablemodel) _transaction(callback func()) {
 
 func (e *Basictablemodel) Save() Basictablemodelinterface {
 	e._transaction(func() {
+		if e.lazyEav != nil {
+			e.lazyEav.load(e)
+		}
 		if m, ok := interface{}(e.Model).(BasicModelBeforeSaveInterface); ok {
 			m.BeforeSave(e)
 		}
+		e.validateRequiredFields()
 		e.ResourceModel.Save()
+		e.saveLocaleData()
+		if e.isTree() {
+			e.saveTree()
+		}
+		e.reindexFlatEntity()
 		if m, ok := interface{}(e.Model).(BasicModelSaveInterface); ok {
 			m.AfterSave(e)
 		}
 	})
+	// 最外層的事務提交後才清除其他 locale 的值， 回滾時下次 Save 再保存
+	if e.LastError == nil && !inTransaction(e.GetConnection()) {
+		e.localeData = nil
+	}
 	return e
 }
 
+func (e *Basictablemodel) validateRequiredFields() {
+	for key, field := range e.GetTableFields() {
+		if !field.IsRequired {
+			continue
+		}
+		value := e.GetData(key)
+		if field.IsMultiValue {
+			if value == nil || len(convertToStringSlice(value)) == 0 {
+				panic(fmt.Errorf("%s is required", key))
+			}
+			continue
+		}
+		if value == nil || ConvertToString(value) == "" {
+			panic(fmt.Errorf("%s is required", key))
+		}
+	}
+}
+
 func (e *Basictablemodel) Delete() Basictablemodelinterface {
 	e._transaction(func() {
 		if m, ok := interface{}(e.Model).(BasicModelDeleteBeforeInterface); ok {
 			m.BeforeDelete(e)
 		}
 		e.ResourceModel.Delete()
+		if e.isTree() {
+			e.deleteTree()
+		}
+		e.deleteFlatEntity(e.GetData(e.GetPrimaryFieldName()))
 		if m, ok := interface{}(e.Model).(BasicModelDeleteInterface); ok {
 			m.AfterDelete(e)
 		}
@@ -126,7 +209,38 @@ func (e *Basictablemodel) Delete() Basictablemodelinterface {
 }
 func (e *Basictablemodel) LoadByField(field string, value interface{}) Basictablemodelinterface {
 	e._transaction(func() {
-		e.ResourceModel.LoadByField(field, value)
+		if rows, ok := e.loadWithDefaultScopes(map[string]map[string]interface{}{field: {"=": value}}, 1); ok {
+			var data map[string]interface{}
+			if len(rows) > 0 {
+				data = rows[0]
+			}
+			e.ResourceModel.LoadDbData(data)
+		} else {
+			e.ResourceModel.LoadByField(field, value)
+		}
+		if m, ok := interface{}(e.Model).(BasicModelLoadInterface); ok {
+			m.AfterLoad(e)
+		}
+	})
+
+	return e
+}
+
+func (e *Basictablemodel) LoadByFields(values map[string]interface{}) Basictablemodelinterface {
+	e._transaction(func() {
+		e.loadOneByFilter(fieldsToFilters(values))
+		if m, ok := interface{}(e.Model).(BasicModelLoadInterface); ok {
+			m.AfterLoad(e)
+		}
+	})
+
+	return e
+}
+
+// filters 格式跟 Collection.AddFieldToFilter 一樣： {"age"：{">="：18, "<": 30}}
+func (e *Basictablemodel) LoadOneByFilter(filters map[string]map[string]interface{}) Basictablemodelinterface {
+	e._transaction(func() {
+		e.loadOneByFilter(filters)
 		if m, ok := interface{}(e.Model).(BasicModelLoadInterface); ok {
 			m.AfterLoad(e)
 		}
@@ -134,6 +248,26 @@ func (e *Basictablemodel) LoadByField(field string, value interface{}) Basictabl
 
 	return e
 }
+
+// 找不到 panic ErrNotFound， 多於一條 panic ErrMultipleResults
+func (e *Basictablemodel) loadOneByFilter(filters map[string]map[string]interface{}) {
+	rows, ok := e.loadWithDefaultScopes(filters, 2)
+	if !ok {
+		e.ResourceModel.LoadOneByFilter(filters)
+		return
+	}
+	switch len(rows) {
+	case 0:
+		e.ResourceModel.LoadDbData(nil)
+		panic(ErrNotFound)
+	case 1:
+		e.ResourceModel.LoadDbData(rows[0])
+	default:
+		e.ResourceModel.LoadDbData(nil)
+		panic(ErrMultipleResults)
+	}
+}
+
 func (e *Basictablemodel) LoadById(id interface{}) Basictablemodelinterface {
 	e.LoadByField(e.GetPrimaryFieldName(), id)
 	return e
@@ -155,7 +289,34 @@ func (e *Basictablemodel) GetTableName() string {
 }
 
 func (e *Basictablemodel) GetTableFields() map[string]Field {
-	return e.Model.GetTableFields()
+	fields := e.Model.GetTableFields()
+	if m, ok := interface{}(e.Model).(IsRuntimeEavInterface); !ok || !m.IsRuntimeEav() {
+		return fields
+	}
+	// 合併 eav_attribute 表中的字段， 同名時 GetTableFields 優先
+	// 用 model 的連接查詢， 在事務中也能看到未提交的字段
+	var connection DBConnectionInterface
+	if e.ResourceModel != nil {
+		connection = e.GetConnection()
+	}
+	attributes, err := getEavAttributes(connection, e.GetConnectionName(), e.Model.GetTableName())
+	if err != nil {
+		// 查詢失敗時只返回 GetTableFields 中的字段， 錯誤保存在 LastError
+		if e.LastError == nil {
+			e.LastError = err
+		}
+		return fields
+	}
+	merged := make(map[string]Field, len(fields)+len(attributes))
+	for key, field := range fields {
+		merged[key] = field
+	}
+	for _, attribute := range attributes {
+		if _, ok := merged[attribute.Code]; !ok {
+			merged[attribute.Code] = attribute.ToField()
+		}
+	}
+	return merged
 }
 func (e *Basictablemodel) Init() Basictablemodelinterface {
 	e.ResourceModel = &basictableResource{}
@@ -167,6 +328,7 @@ func (e *Basictablemodel) GetResourceModel() BasictableResourceInterface {
 }
 
 func (e *Basictablemodel) SetData(field string, value interface{}) Basictablemodelinterface {
+	e.loadLazyEav(field)
 	e.GetResourceModel().SetData(field, value)
 	return e
 }
@@ -175,6 +337,7 @@ func (e *Basictablemodel) GetModel() BasicModelInterface {
 }
 
 func (e *Basictablemodel) GetData(field string) interface{} {
+	e.loadLazyEav(field)
 	value := e.GetResourceModel().GetData(field)
 	return value
 }
@@ -199,8 +362,19 @@ func (e *Basictablemodel) GetDefaultLocale() string {
 }
 func (e *Basictablemodel) GetEavFields() map[string]Field {
 	fields := make(map[string]Field)
-	for key, field := range e.Model.GetTableFields() {
+	var codes map[string]bool
+	// attribute set 加載失敗時設置 LastError， 返回所有字段
+	if set, err := e.GetAttributeSet(); err != nil {
+		e.LastError = err
+	} else if set != nil {
+		codes = set.GetAttributeCodes()
+	}
+	for key, field := range e.GetTableFields() {
 		if field.IsEav && field.EavType != "" {
+			// 有 attribute set 時只返回分配的字段
+			if codes != nil && !codes[key] {
+				continue
+			}
 			fields[key] = field
 		}
 	}
@@ -213,3 +387,32 @@ func (e *Basictablemodel) GetDeleteFields() []string {
 	}
 	return result
 }
+
+func (e *Basictablemodel) GetRelations() map[string]Relation {
+	if m, ok := interface{}(e.Model).(BasicModelRelationsInterface); ok {
+		return m.GetRelations()
+	}
+	return make(map[string]Relation)
+}
+
+// 返回已加載的關聯， 未加載時返回 nil
+func (e *Basictablemodel) GetRelated(name string) []Basictablemodelinterface {
+	return e.Related[name]
+}
+
+func (e *Basictablemodel) SetRelated(name string, elems []Basictablemodelinterface) Basictablemodelinterface {
+	if e.Related == nil {
+		e.Related = make(map[string][]Basictablemodelinterface)
+	}
+	e.Related[name] = elems
+	return e
+}
+
+func (e *Basictablemodel) LoadRelation(names ...string) Basictablemodelinterface {
+	e._transaction(func() {
+		for _, name := range names {
+			loadRelation([]Basictablemodelinterface{e}, name)
+		}
+	})
+	return e
+}
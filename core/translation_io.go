@@ -0,0 +1,315 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	TranslationFormatXliff = "xliff" // XLIFF 2.0， unit id 是 "<entity id>:<attribute>" (NMTOKEN 不能包含 "/")
+	TranslationFormatCsv   = "csv"   // entity_id, attribute, <source locale>, <target locale>
+)
+
+// SourceLocale 為空時用 collection 的 locale (元素加載的值， 包括 fallback)， 不一樣時用 SourceLocale 保存的值， Fields 為空時用所有可以翻譯的 eav 字段 (不包括 IsGlobal 和多值字段)
+type TranslationExportOptions struct {
+	Format       string
+	SourceLocale string
+	TargetLocale string
+	Fields       []string
+}
+
+type TranslationImportOptions struct {
+	Format string
+	DryRun bool // 只檢查， 不保存
+}
+
+// Line: csv 的行號 (從 1 開始， 包括標題)， xliff 的 unit 序號 (從 1 開始)
+type TranslationImportError struct {
+	Line      int
+	EntityId  string
+	Attribute string
+	Message   string
+}
+
+type TranslationImportResult struct {
+	Locale   string
+	Imported int // 保存 (dry run 時可以保存) 的值
+	Skipped  int // target 為空的行
+	Errors   []TranslationImportError
+}
+
+type translationRow struct {
+	Line      int
+	EntityId  string
+	Attribute string
+	Source    string
+	Target    string
+}
+
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Id    string      `xml:"id,attr"`
+	Units []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	Id      string       `xml:"id,attr"`
+	Segment xliffSegment `xml:"segment"`
+}
+
+type xliffSegment struct {
+	Source string `xml:"source"`
+	Target string `xml:"target"`
+}
+
+// 保存 locale 的值， 例如導入翻譯
+func (e *Basictablemodel) SaveForLocale(locale string, values map[string]interface{}) Basictablemodelinterface {
+	for field, value := range values {
+		e.SetDataForLocale(locale, field, value)
+	}
+	return e.Save()
+}
+
+// 可以翻譯的 eav 字段， 不包括 IsGlobal 和多值字段
+func translatableFields(model Basictablemodelinterface) map[string]Field {
+	fields := make(map[string]Field)
+	for key, field := range model.GetEavFields() {
+		if !field.IsGlobal && !field.IsMultiValue {
+			fields[key] = field
+		}
+	}
+	return fields
+}
+
+// 一個 locale 的值， 每個 value 表一條查詢， 返回 entity id => field => value
+func loadLocaleValues(model Basictablemodelinterface, ids []interface{}, locale string, fields map[string]Field) map[string]map[string]string {
+	result := make(map[string]map[string]string)
+	if len(ids) == 0 {
+		return result
+	}
+	connection := model.GetConnection()
+	for _, eavType := range eavFieldTypes(fields) {
+		sql := "select entity_id, attribute_name, " + eavValueAsChar(eavType, "value") + " as value from " + model.GetTableName() + "_" + eavType + " where locale = ? and scope = '' and position = 0 and entity_id in (?)"
+		for _, row := range connection.Fetch(connection.Expr(sql, locale, ids)) {
+			id := ConvertToString(row["entity_id"])
+			if _, ok := result[id]; !ok {
+				result[id] = make(map[string]string)
+			}
+			result[id][ConvertToString(row["attribute_name"])] = ConvertToString(row["value"])
+		}
+	}
+	return result
+}
+
+// 導出 collection 元素的 eav 字段， source 見 TranslationExportOptions.SourceLocale， target 是 TargetLocale 已有的翻譯
+func ExportTranslations(collection CollectionInterface, w io.Writer, options TranslationExportOptions) error {
+	if options.Format != TranslationFormatCsv && options.Format != TranslationFormatXliff {
+		return fmt.Errorf("unknown translation format %s", options.Format)
+	}
+	rows := make([]translationRow, 0)
+	model := collection.Create()
+	sourceLocale := options.SourceLocale
+	if sourceLocale == "" {
+		sourceLocale = model.GetLocale()
+	}
+	err := recoverError(func() {
+		if options.TargetLocale == "" {
+			panic(fmt.Errorf("target locale is required"))
+		}
+		fields := translatableFields(model)
+		names := make([]string, 0)
+		if len(options.Fields) == 0 {
+			for key := range fields {
+				names = append(names, key)
+			}
+		} else {
+			for _, name := range options.Fields {
+				name = strings.ToLower(name)
+				if _, ok := fields[name]; !ok {
+					panic(fmt.Errorf("%s is not a translatable eav field", name))
+				}
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		elems := collection.GetElems()
+		if err := collection.GetLastError(); err != nil {
+			panic(err)
+		}
+		ids := make([]interface{}, 0, len(elems))
+		for _, elem := range elems {
+			ids = append(ids, elem.GetData(elem.GetPrimaryFieldName()))
+		}
+		targets := loadLocaleValues(model, ids, options.TargetLocale, fields)
+		var sources map[string]map[string]string
+		if sourceLocale != model.GetLocale() {
+			sources = loadLocaleValues(model, ids, sourceLocale, fields)
+		}
+		for _, elem := range elems {
+			id := ConvertToString(elem.GetData(elem.GetPrimaryFieldName()))
+			for _, name := range names {
+				source := ConvertToString(elem.GetData(name))
+				if sources != nil {
+					source = sources[id][name]
+				}
+				rows = append(rows, translationRow{EntityId: id, Attribute: name, Source: source, Target: targets[id][name]})
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	switch options.Format {
+	case TranslationFormatCsv:
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"entity_id", "attribute", sourceLocale, options.TargetLocale})
+		for _, row := range rows {
+			writer.Write([]string{row.EntityId, row.Attribute, row.Source, row.Target})
+		}
+		writer.Flush()
+		return writer.Error()
+	case TranslationFormatXliff:
+		document := xliffDocument{Version: "2.0", SrcLang: sourceLocale, TrgLang: options.TargetLocale, Files: []xliffFile{{Id: model.GetTableName()}}}
+		for _, row := range rows {
+			document.Files[0].Units = append(document.Files[0].Units, xliffUnit{Id: row.EntityId + ":" + row.Attribute, Segment: xliffSegment{Source: row.Source, Target: row.Target}})
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		return encoder.Encode(document)
+	}
+	return fmt.Errorf("unknown translation format %s", options.Format)
+}
+
+// 讀取文件， 返回 target locale 和所有行
+func readTranslations(r io.Reader, format string) (string, []translationRow, error) {
+	rows := make([]translationRow, 0)
+	switch format {
+	case TranslationFormatCsv:
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return "", nil, err
+		}
+		if len(records) == 0 || len(records[0]) < 4 {
+			return "", nil, fmt.Errorf("csv header must be entity_id, attribute, source locale, target locale")
+		}
+		for index, record := range records[1:] {
+			row := translationRow{Line: index + 2}
+			if len(record) >= 4 {
+				row.EntityId, row.Attribute, row.Source, row.Target = record[0], record[1], record[2], record[3]
+			}
+			rows = append(rows, row)
+		}
+		return records[0][3], rows, nil
+	case TranslationFormatXliff:
+		document := xliffDocument{}
+		if err := xml.NewDecoder(r).Decode(&document); err != nil {
+			return "", nil, err
+		}
+		line := 0
+		for _, file := range document.Files {
+			for _, unit := range file.Units {
+				line++
+				row := translationRow{Line: line, Source: unit.Segment.Source, Target: unit.Segment.Target}
+				row.EntityId, row.Attribute, _ = strings.Cut(unit.Id, ":")
+				rows = append(rows, row)
+			}
+		}
+		return document.TrgLang, rows, nil
+	}
+	return "", nil, fmt.Errorf("unknown translation format %s", format)
+}
+
+// 導入翻譯， 每個實體用 SaveForLocale 保存， 檢查 entity id 和字段， 錯誤按行返回
+// 只有文件不能讀取時返回 error
+func ImportTranslations(model Basictablemodelinterface, r io.Reader, options TranslationImportOptions) (*TranslationImportResult, error) {
+	locale, rows, err := readTranslations(r, options.Format)
+	if err != nil {
+		return nil, err
+	}
+	if locale == "" {
+		return nil, fmt.Errorf("target locale is required")
+	}
+	result := &TranslationImportResult{Locale: locale, Errors: make([]TranslationImportError, 0)}
+	addError := func(row translationRow, message string) {
+		result.Errors = append(result.Errors, TranslationImportError{Line: row.Line, EntityId: row.EntityId, Attribute: row.Attribute, Message: message})
+	}
+	err = recoverError(func() {
+		fields := translatableFields(model)
+		ids := make([]interface{}, 0)
+		for _, row := range rows {
+			if row.EntityId != "" {
+				ids = append(ids, row.EntityId)
+			}
+		}
+		existing := make(map[string]bool)
+		if len(ids) > 0 {
+			connection := model.GetConnection()
+			primaryField := model.GetPrimaryFieldName()
+			for _, row := range connection.Fetch(connection.Expr("select "+primaryField+" from "+model.GetTableName()+" where "+primaryField+" in (?)", ids)) {
+				existing[ConvertToString(row[primaryField])] = true
+			}
+		}
+
+		values := make(map[string]map[string]interface{})
+		entityRows := make(map[string][]translationRow)
+		entityIds := make([]string, 0)
+		for _, row := range rows {
+			row.Attribute = strings.ToLower(row.Attribute)
+			if !existing[row.EntityId] {
+				addError(row, "entity not found")
+				continue
+			}
+			if _, ok := fields[row.Attribute]; !ok {
+				addError(row, "unknown attribute")
+				continue
+			}
+			if row.Target == "" {
+				result.Skipped++
+				continue
+			}
+			if _, ok := values[row.EntityId]; !ok {
+				values[row.EntityId] = make(map[string]interface{})
+				entityIds = append(entityIds, row.EntityId)
+			}
+			values[row.EntityId][row.Attribute] = row.Target
+			entityRows[row.EntityId] = append(entityRows[row.EntityId], row)
+		}
+		if options.DryRun {
+			for _, id := range entityIds {
+				result.Imported += len(entityRows[id])
+			}
+			return
+		}
+		factory := selfFactory(model)
+		for _, id := range entityIds {
+			entity := factory(model.GetLocale(), model.GetDefaultLocale())
+			entity.GetConnection().SetDb(model.GetConnection().GetDb())
+			entity.LoadById(id).SaveForLocale(locale, values[id])
+			if err := entity.GetLastError(); err != nil {
+				for _, row := range entityRows[id] {
+					addError(row, err.Error())
+				}
+				continue
+			}
+			result.Imported += len(entityRows[id])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
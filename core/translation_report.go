@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 翻譯狀態
+const (
+	TranslationMissing   = "missing"   // default locale 有值， locale 沒有值
+	TranslationOutdated  = "outdated"  // locale 的值保存後 default locale 的值修改過
+	TranslationIdentical = "identical" // locale 的值跟 default locale 一樣
+)
+
+type TranslationIssue struct {
+	EntityId         uint64
+	Attribute        string
+	Locale           string
+	Status           string
+	DefaultValue     string
+	Value            string
+	DefaultUpdatedAt string
+	UpdatedAt        string
+}
+
+// Locales 為空時用 value 表中除了 default locale 的所有 locale (不包括 scope)
+// Fields 為空時用所有非 IsGlobal 的 eav 字段， Statuses 為空時返回所有狀態， PageSize 為 0 時不分頁
+type TranslationReportOptions struct {
+	Locales  []string
+	Fields   []string
+	Statuses []string
+	Page     int
+	PageSize int
+}
+
+type TranslationReport struct {
+	Issues   []TranslationIssue
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// 按 entity id, attribute, locale 排序， 多值字段只比較第一個值
+func GetTranslationReport(model Basictablemodelinterface, options TranslationReportOptions) (*TranslationReport, error) {
+	report := &TranslationReport{Issues: make([]TranslationIssue, 0), Page: options.Page, PageSize: options.PageSize}
+	if report.Page < 1 {
+		report.Page = 1
+	}
+	err := recoverError(func() {
+		defaultLocale := model.GetDefaultLocale()
+		if defaultLocale == "" {
+			panic(fmt.Errorf("default locale is required"))
+		}
+		fields := translationReportFields(model, options.Fields)
+		if len(fields) == 0 {
+			return
+		}
+		connection := model.GetConnection()
+		locales := options.Locales
+		if len(locales) == 0 {
+			locales = translationReportLocales(model, fields)
+		}
+		if len(locales) == 0 {
+			return
+		}
+
+		sqls := make([]string, 0)
+		values := make([]interface{}, 0)
+		localeSql := "select ? as locale" + strings.Repeat(" union all select ?", len(locales)-1)
+		for _, eavType := range eavFieldTypes(fields) {
+			names := make([]string, 0)
+			for key, field := range fields {
+				if field.EavType == eavType {
+					names = append(names, key)
+				}
+			}
+			sort.Strings(names)
+			table := model.GetTableName() + "_" + eavType
+			sqls = append(sqls, "select d.entity_id, d.attribute_name, l.locale, "+eavValueAsChar(eavType, "d.value")+" as default_value, "+eavValueAsChar(eavType, "t.value")+" as value,"+
+				" d.updated_at as default_updated_at, t.updated_at,"+
+				" case when t.value is null then '"+TranslationMissing+"' when d.updated_at > t.updated_at then '"+TranslationOutdated+"' when binary t.value = binary d.value then '"+TranslationIdentical+"' end as status"+
+				" from "+table+" as d cross join ("+localeSql+") as l"+
+				" left join "+table+" as t on t.entity_id = d.entity_id and t.attribute_name = d.attribute_name and t.position = d.position and t.locale = l.locale and t.scope = ''"+
+				" where d.locale = ? and d.scope = '' and d.position = 0 and d.value is not null and d.attribute_name in ("+strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")+")")
+			values = append(values, stringsToInterfaces(locales)...)
+			values = append(values, defaultLocale)
+			values = append(values, stringsToInterfaces(names)...)
+		}
+		where := " where r.status is not null"
+		if len(options.Statuses) > 0 {
+			where += " and r.status in (" + strings.TrimSuffix(strings.Repeat("?,", len(options.Statuses)), ",") + ")"
+			values = append(values, stringsToInterfaces(options.Statuses)...)
+		}
+		from := " from (" + strings.Join(sqls, " union all ") + ") as r" + where
+		report.Total = ConvertToInt64(connection.FetchOne(connection.Expr("select count(*)"+from, values...)))
+		sql := "select r.*" + from + " order by r.entity_id, r.attribute_name, r.locale"
+		if report.PageSize > 0 {
+			sql += fmt.Sprintf(" limit %d offset %d", report.PageSize, (report.Page-1)*report.PageSize)
+		}
+		for _, row := range connection.Fetch(connection.Expr(sql, values...)) {
+			report.Issues = append(report.Issues, TranslationIssue{
+				EntityId:         ConvertToUint64(row["entity_id"]),
+				Attribute:        ConvertToString(row["attribute_name"]),
+				Locale:           ConvertToString(row["locale"]),
+				Status:           ConvertToString(row["status"]),
+				DefaultValue:     ConvertToString(row["default_value"]),
+				Value:            ConvertToString(row["value"]),
+				DefaultUpdatedAt: ConvertToTimeString(row["default_updated_at"]),
+				UpdatedAt:        ConvertToTimeString(row["updated_at"]),
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func translationReportFields(model Basictablemodelinterface, names []string) map[string]Field {
+	fields := make(map[string]Field)
+	for key, field := range model.GetEavFields() {
+		if field.IsGlobal {
+			continue
+		}
+		fields[key] = field
+	}
+	if len(names) == 0 {
+		return fields
+	}
+	selected := make(map[string]Field)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		field, ok := fields[name]
+		if !ok {
+			panic(fmt.Errorf("%s is not a translatable eav field", name))
+		}
+		selected[name] = field
+	}
+	return selected
+}
+
+// value 表中除了 default locale 的所有 locale， 不包括 scope
+func translationReportLocales(model Basictablemodelinterface, fields map[string]Field) []string {
+	connection := model.GetConnection()
+	sqls := make([]string, 0)
+	for _, eavType := range eavFieldTypes(fields) {
+		sqls = append(sqls, "select distinct locale from "+model.GetTableName()+"_"+eavType+" where scope = ''")
+	}
+	locales := make([]string, 0)
+	for _, row := range connection.Fetch(connection.Expr("select distinct locale from ("+strings.Join(sqls, " union ")+") as l where locale <> ? order by locale", model.GetDefaultLocale())) {
+		locales = append(locales, ConvertToString(row["locale"]))
+	}
+	return locales
+}
+
+// 導出 csv， 第一行是標題
+func (r *TranslationReport) WriteCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"entity_id", "attribute", "locale", "status", "default_value", "value", "default_updated_at", "updated_at"})
+	for _, issue := range r.Issues {
+		writer.Write([]string{ConvertToString(issue.EntityId), issue.Attribute, issue.Locale, issue.Status, issue.DefaultValue, issue.Value, issue.DefaultUpdatedAt, issue.UpdatedAt})
+	}
+	writer.Flush()
+	return writer.Error()
+}
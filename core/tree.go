@@ -0,0 +1,212 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 樹形 model 的字段， 必須在 GetTableFields 中定義
+// path 是 materialized path， 例如 "1/4/9"， root 的 level 是 0
+const (
+	TreeParentField   = "parent_id"
+	TreePathField     = "path"
+	TreeLevelField    = "level"
+	TreePositionField = "position"
+)
+
+var ErrTreeCycle = errors.New("cannot move a node into its own subtree")
+
+func (e *Basictablemodel) isTree() bool {
+	if m, ok := interface{}(e.Model).(IsTreeInterface); ok {
+		return m.IsTree()
+	}
+	return false
+}
+
+// parent_id 為空或者 0 時是 root
+func treeParentId(value interface{}) interface{} {
+	if value == nil || ConvertToString(value) == "" || ConvertToString(value) == "0" {
+		return nil
+	}
+	return ConvertToString(value)
+}
+
+func treeParentCondition(parentId interface{}) (string, []interface{}) {
+	if parentId == nil {
+		return TreeParentField + " is null", []interface{}{}
+	}
+	return TreeParentField + " = ?", []interface{}{parentId}
+}
+
+// 在 Save 的事務中執行， 維護 path, level, position 和子孫節點的 path
+func (e *Basictablemodel) saveTree() {
+	connection := e.GetConnection()
+	table := e.Model.GetTableName()
+	primaryField := e.GetPrimaryFieldName()
+	id := ConvertToString(e.GetData(primaryField))
+	if id == "" {
+		return
+	}
+	parentId := treeParentId(e.GetData(TreeParentField))
+	resource := e.GetResourceModel()
+
+	path := id
+	level := 0
+	if parentId != nil {
+		row := connection.FetchRow(connection.Expr("select "+TreePathField+", "+TreeLevelField+" from "+table+" where "+primaryField+" = ?", parentId))
+		if row == nil {
+			panic(fmt.Errorf("parent %v not found", parentId))
+		}
+		parentPath := ConvertToString(row[TreePathField])
+		if parentPath == id || strings.HasPrefix(parentPath, id+"/") || strings.Contains(parentPath, "/"+id+"/") || strings.HasSuffix(parentPath, "/"+id) {
+			panic(ErrTreeCycle)
+		}
+		path = parentPath + "/" + id
+		level = ConvertToInt(row[TreeLevelField]) + 1
+	}
+
+	oldPath := ConvertToString(resource.GetOriginData(TreePathField))
+	oldLevel := ConvertToInt(resource.GetOriginData(TreeLevelField))
+	oldParentId := treeParentId(resource.GetOriginData(TreeParentField))
+	oldPosition := resource.GetOriginData(TreePositionField)
+	isNew := oldPath == ""
+
+	// 換了 parent 或者 position 時， 先從舊的位置移走， 再在新的位置騰出空位
+	position := e.GetData(TreePositionField)
+	parentChanged := !isNew && ConvertToString(oldParentId) != ConvertToString(parentId)
+	positionChanged := !isNew && (position == nil || ConvertToInt(position) != ConvertToInt(oldPosition))
+	if (parentChanged || positionChanged) && oldPosition != nil {
+		e.shiftTreePositions(oldParentId, ConvertToInt(oldPosition)+1, -1)
+	}
+	if isNew || parentChanged || positionChanged {
+		if position != nil && ConvertToInt(position) > 0 && (isNew || positionChanged) {
+			e.shiftTreePositions(parentId, position, 1)
+		} else {
+			// 只換了 parent 時放到最後
+			position = e.nextTreePosition(parentId)
+		}
+	}
+
+	data := map[string]interface{}{TreePathField: path, TreeLevelField: level, TreePositionField: ConvertToInt(position)}
+	connection.Update(table, data, connection.Expr(primaryField+" = ?", id))
+	if oldPath != "" && oldPath != path {
+		// 子孫節點不經過 Save， flat 表過期
+		e.invalidateFlatIndex()
+		connection.Exec("update "+table+" set "+TreePathField+" = concat(?, substring("+TreePathField+", ?)), "+TreeLevelField+" = "+TreeLevelField+" + ? where "+TreePathField+" like ?", path, len(oldPath)+1, level-oldLevel, oldPath+"/%")
+	}
+	for key, value := range data {
+		resource.SetData(key, value)
+		resource.SetOriginData(key, value)
+	}
+	resource.SetOriginData(TreeParentField, e.GetData(TreeParentField))
+}
+
+func (e *Basictablemodel) nextTreePosition(parentId interface{}) int {
+	connection := e.GetConnection()
+	condition, values := treeParentCondition(parentId)
+	values = append(values, e.GetData(e.GetPrimaryFieldName()))
+	return ConvertToInt(connection.FetchOne(connection.Expr("select coalesce(max("+TreePositionField+"), 0) + 1 from "+e.Model.GetTableName()+" where "+condition+" and not "+e.GetPrimaryFieldName()+" <=> ?", values...)))
+}
+
+// 兄弟節點的 position 從 position 開始移動 offset
+func (e *Basictablemodel) shiftTreePositions(parentId interface{}, position interface{}, offset int) {
+	condition, values := treeParentCondition(parentId)
+	values = append(values, offset, position, e.GetData(e.GetPrimaryFieldName()))
+	if e.GetConnection().Exec("update "+e.Model.GetTableName()+" set "+TreePositionField+" = "+TreePositionField+" + ? where "+condition+" and "+TreePositionField+" >= ? and not "+e.GetPrimaryFieldName()+" <=> ?", values...) > 0 {
+		e.invalidateFlatIndex()
+	}
+}
+
+// 在 Delete 的事務中執行， 兄弟節點的 position 補位
+// 子孫節點按 path 刪除， 不依賴 parent_id 外鍵的 ON DELETE CASCADE
+func (e *Basictablemodel) deleteTree() {
+	e.shiftTreePositions(treeParentId(e.GetData(TreeParentField)), ConvertToInt(e.GetData(TreePositionField))+1, -1)
+	path := ConvertToString(e.GetData(TreePathField))
+	if path == "" {
+		return
+	}
+	if e.GetConnection().Exec("delete from "+e.Model.GetTableName()+" where "+TreePathField+" like ?", path+"/%") > 0 {
+		e.invalidateFlatIndex()
+	}
+}
+
+// 移動到 parent 下的 position (從 1 開始)， parent 為 nil 時移動到 root， position <= 0 時放到最後
+func (e *Basictablemodel) MoveTo(parent Basictablemodelinterface, position int) Basictablemodelinterface {
+	e._transaction(func() {
+		if !e.isTree() {
+			panic(fmt.Errorf("model %s is not a tree", e.Model.GetTableName()))
+		}
+		var parentId interface{}
+		if parent != nil {
+			parentId = treeParentId(parent.GetData(parent.GetPrimaryFieldName()))
+		}
+		resource := e.GetResourceModel()
+		// 兄弟節點移動後內存中的 position 可能已經過期， 用數據庫中的值
+		connection := e.GetConnection()
+		row := connection.FetchRow(connection.Expr("select "+TreeParentField+", "+TreePathField+", "+TreeLevelField+", "+TreePositionField+" from "+e.Model.GetTableName()+" where "+e.GetPrimaryFieldName()+" = ?", e.GetData(e.GetPrimaryFieldName())))
+		for key, value := range row {
+			resource.SetOriginData(key, value)
+		}
+		// 兄弟節點的 position 由 saveTree 維護
+		e.SetData(TreeParentField, parentId)
+		if position > 0 {
+			e.SetData(TreePositionField, position)
+		} else {
+			e.SetData(TreePositionField, nil)
+		}
+		e.Save()
+		if e.LastError != nil {
+			panic(e.LastError)
+		}
+	})
+	return e
+}
+
+func (e *Basictablemodel) newTreeCollection() CollectionInterface {
+	return newRelationCollection(e, selfFactory(e))
+}
+
+// 直接子節點， 按 position 排序
+func (e *Basictablemodel) GetChildren() CollectionInterface {
+	collection := e.newTreeCollection()
+	collection.AddFieldToFilter(map[string]map[string]interface{}{TreeParentField: {"=": e.GetData(e.GetPrimaryFieldName())}})
+	collection.AddOrder(TreePositionField, "asc")
+	return collection
+}
+
+// 所有子孫節點， 按 level, position 排序
+func (e *Basictablemodel) GetDescendants() CollectionInterface {
+	collection := e.newTreeCollection()
+	collection.AddFieldToFilter(map[string]map[string]interface{}{TreePathField: {"like": ConvertToString(e.GetData(TreePathField)) + "/%"}})
+	collection.AddOrder(TreeLevelField, "asc").AddOrder(TreePositionField, "asc")
+	return collection
+}
+
+// 所有祖先節點， 從 root 開始
+func (e *Basictablemodel) GetAncestors() CollectionInterface {
+	collection := e.newTreeCollection()
+	ids := strings.Split(ConvertToString(e.GetData(TreePathField)), "/")
+	ids = ids[:len(ids)-1]
+	if len(ids) == 0 {
+		ids = []string{"0"}
+	}
+	collection.AddFieldToFilter(map[string]map[string]interface{}{e.GetPrimaryFieldName(): {"in": ids}})
+	collection.AddOrder(TreeLevelField, "asc")
+	return collection
+}
+
+// 過濾 rootId 的子樹
+func (e *Collection) AddSubtreeFilter(rootId interface{}, includeRoot bool) CollectionInterface {
+	primaryField := e.Model.GetPrimaryFieldName()
+	rootPath := "(select " + TreePathField + " from " + e.Model.GetTableName() + " where " + primaryField + " = ?)"
+	sql := "(e." + TreePathField + " like concat(" + rootPath + ", '/%')"
+	values := []interface{}{rootId}
+	if includeRoot {
+		sql += " or e." + primaryField + " = ?"
+		values = append(values, rootId)
+	}
+	sql += ")"
+	e.DbSelect.Where(e.Connection.Expr(sql, values...))
+	return e
+}
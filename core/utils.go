@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"time"
@@ -303,3 +305,21 @@ func updateField(obj interface{}, fieldName string, value interface{}) {
 	field.Set(reflect.ValueOf(value))
 
 }
+
+// 把 callback 中的 panic 轉為 error， 跟 _transaction 的處理一樣
+func recoverError(callback func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch x := r.(type) {
+			case string:
+				err = errors.New(x)
+			case error:
+				err = x
+			default:
+				err = fmt.Errorf("unexpected error: %v", r)
+			}
+		}
+	}()
+	callback()
+	return nil
+}
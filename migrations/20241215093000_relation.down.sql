@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `user_role`;
+DROP TABLE IF EXISTS `role_varchar`;
+DROP TABLE IF EXISTS `role`;
+DROP TABLE IF EXISTS `address_varchar`;
+DROP TABLE IF EXISTS `address`;
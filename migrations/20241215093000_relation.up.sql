@@ -0,0 +1,39 @@
+  CREATE TABLE IF NOT EXISTS `address` (
+  `entity_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `postcode` varchar(32) null,
+   PRIMARY KEY (`entity_id`),
+   CONSTRAINT address_user_id_user_entity_id FOREIGN KEY (`user_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `address_varchar` (
+  `entity_id` bigint unsigned NOT NULL,
+  `locale` varchar(255),
+  `attribute_name` varchar(255),
+  `value`  varchar(255),
+   CONSTRAINT address_varchar_entity_id_address_entity_id FOREIGN KEY (`entity_id`) REFERENCES `address`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+     UNIQUE KEY address_varchar_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `role` (
+  `entity_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `code` varchar(64) not null,
+   PRIMARY KEY (`entity_id`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `role_varchar` (
+  `entity_id` bigint unsigned NOT NULL,
+  `locale` varchar(255),
+  `attribute_name` varchar(255),
+  `value`  varchar(255),
+   CONSTRAINT role_varchar_entity_id_role_entity_id FOREIGN KEY (`entity_id`) REFERENCES `role`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+     UNIQUE KEY role_varchar_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `user_role` (
+  `user_id` bigint unsigned NOT NULL,
+  `role_id` bigint unsigned NOT NULL,
+   PRIMARY KEY (`user_id`, `role_id`),
+   CONSTRAINT user_role_user_id_user_entity_id FOREIGN KEY (`user_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+   CONSTRAINT user_role_role_id_role_entity_id FOREIGN KEY (`role_id`) REFERENCES `role`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,25 @@
+  CREATE TABLE IF NOT EXISTS `comment` (
+  `entity_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `commentable_type` varchar(64) not null,
+  `commentable_id` bigint unsigned not null,
+  `body` varchar(255) null,
+   PRIMARY KEY (`entity_id`),
+   KEY comment_commentable_type_commentable_id (`commentable_type`, `commentable_id`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `category` (
+  `entity_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `parent_id` bigint unsigned null,
+  `code` varchar(64) not null,
+   PRIMARY KEY (`entity_id`),
+   CONSTRAINT category_parent_id_category_entity_id FOREIGN KEY (`parent_id`) REFERENCES `category`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `category_varchar` (
+  `entity_id` bigint unsigned NOT NULL,
+  `locale` varchar(255),
+  `attribute_name` varchar(255),
+  `value`  varchar(255),
+   CONSTRAINT category_varchar_entity_id_category_entity_id FOREIGN KEY (`entity_id`) REFERENCES `category`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+     UNIQUE KEY category_varchar_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE `category`
+  DROP KEY category_path,
+  DROP COLUMN `position`,
+  DROP COLUMN `level`,
+  DROP COLUMN `path`;
@@ -0,0 +1,5 @@
+ALTER TABLE `category`
+  ADD COLUMN `path` varchar(255) null,
+  ADD COLUMN `level` int unsigned null,
+  ADD COLUMN `position` int unsigned null,
+  ADD KEY category_path (`path`);
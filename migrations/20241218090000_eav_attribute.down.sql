@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `product_varchar`;
+DROP TABLE IF EXISTS `product`;
+DROP TABLE IF EXISTS `eav_attribute_label`;
+DROP TABLE IF EXISTS `eav_attribute`;
@@ -0,0 +1,34 @@
+  CREATE TABLE IF NOT EXISTS `eav_attribute` (
+  `attribute_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `entity_type` varchar(64) not null,
+  `attribute_code` varchar(255) not null,
+  `backend_type` varchar(32) not null,
+  `is_required` tinyint(1) not null default 0,
+  `scope` varchar(16) not null default 'locale',
+  `sort_order` int not null default 0,
+   PRIMARY KEY (`attribute_id`),
+   UNIQUE KEY eav_attribute_entity_type_attribute_code (`entity_type`,`attribute_code`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `eav_attribute_label` (
+  `attribute_id` bigint unsigned NOT NULL,
+  `locale` varchar(255) not null,
+  `label` varchar(255),
+   CONSTRAINT eav_attribute_label_attribute_id FOREIGN KEY (`attribute_id`) REFERENCES `eav_attribute`(`attribute_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+   UNIQUE KEY eav_attribute_label_attribute_id_locale (`attribute_id`,`locale`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `product` (
+  `entity_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `sku` varchar(64) not null,
+   PRIMARY KEY (`entity_id`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `product_varchar` (
+  `entity_id` bigint unsigned NOT NULL,
+  `locale` varchar(255),
+  `attribute_name` varchar(255),
+  `value`  varchar(255),
+   CONSTRAINT product_varchar_entity_id_product_entity_id FOREIGN KEY (`entity_id`) REFERENCES `product`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+     UNIQUE KEY product_varchar_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,6 @@
+ALTER TABLE `product`
+  DROP FOREIGN KEY product_attribute_set_id,
+  DROP COLUMN `attribute_set_id`;
+DROP TABLE IF EXISTS `eav_entity_attribute`;
+DROP TABLE IF EXISTS `eav_attribute_group`;
+DROP TABLE IF EXISTS `eav_attribute_set`;
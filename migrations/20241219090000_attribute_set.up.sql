@@ -0,0 +1,30 @@
+  CREATE TABLE IF NOT EXISTS `eav_attribute_set` (
+  `attribute_set_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `entity_type` varchar(64) not null,
+  `name` varchar(255) not null,
+   PRIMARY KEY (`attribute_set_id`),
+   UNIQUE KEY eav_attribute_set_entity_type_name (`entity_type`,`name`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `eav_attribute_group` (
+  `attribute_group_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `attribute_set_id` bigint unsigned NOT NULL,
+  `name` varchar(255) not null,
+  `sort_order` int not null default 0,
+   PRIMARY KEY (`attribute_group_id`),
+   CONSTRAINT eav_attribute_group_attribute_set_id FOREIGN KEY (`attribute_set_id`) REFERENCES `eav_attribute_set`(`attribute_set_id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `eav_entity_attribute` (
+  `attribute_set_id` bigint unsigned NOT NULL,
+  `attribute_group_id` bigint unsigned NOT NULL,
+  `attribute_code` varchar(255) not null,
+  `sort_order` int not null default 0,
+   UNIQUE KEY eav_entity_attribute_attribute_set_id_attribute_code (`attribute_set_id`,`attribute_code`),
+   CONSTRAINT eav_entity_attribute_attribute_set_id FOREIGN KEY (`attribute_set_id`) REFERENCES `eav_attribute_set`(`attribute_set_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+   CONSTRAINT eav_entity_attribute_attribute_group_id FOREIGN KEY (`attribute_group_id`) REFERENCES `eav_attribute_group`(`attribute_group_id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ALTER TABLE `product`
+  ADD COLUMN `attribute_set_id` bigint unsigned null,
+  ADD CONSTRAINT product_attribute_set_id FOREIGN KEY (`attribute_set_id`) REFERENCES `eav_attribute_set`(`attribute_set_id`) ON DELETE SET NULL ON UPDATE CASCADE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `eav_attribute_option_value`;
+DROP TABLE IF EXISTS `eav_attribute_option`;
+ALTER TABLE `eav_attribute`
+  DROP COLUMN `frontend_input`;
@@ -0,0 +1,19 @@
+ALTER TABLE `eav_attribute`
+  ADD COLUMN `frontend_input` varchar(32) not null default '';
+
+  CREATE TABLE IF NOT EXISTS `eav_attribute_option` (
+  `option_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `entity_type` varchar(64) not null,
+  `attribute_code` varchar(255) not null,
+  `sort_order` int not null default 0,
+   PRIMARY KEY (`option_id`),
+   KEY eav_attribute_option_entity_type_attribute_code (`entity_type`,`attribute_code`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+  CREATE TABLE IF NOT EXISTS `eav_attribute_option_value` (
+  `option_id` bigint unsigned NOT NULL,
+  `locale` varchar(255) not null,
+  `label` varchar(255),
+   CONSTRAINT eav_attribute_option_value_option_id FOREIGN KEY (`option_id`) REFERENCES `eav_attribute_option`(`option_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+   UNIQUE KEY eav_attribute_option_value_option_id_locale (`option_id`,`locale`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `product_text`;
+DROP TABLE IF EXISTS `product_datetime`;
+DROP TABLE IF EXISTS `product_decimal`;
+DROP TABLE IF EXISTS `product_int`;
+DROP TABLE IF EXISTS `user_text`;
+DROP TABLE IF EXISTS `user_datetime`;
+DROP TABLE IF EXISTS `user_decimal`;
+DROP TABLE IF EXISTS `user_int`;
@@ -0,0 +1,72 @@
+ CREATE TABLE IF NOT EXISTS `user_int` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  bigint,
+    CONSTRAINT user_int_entity_id_user_entity_id FOREIGN KEY (`entity_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY user_int_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `user_decimal` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  decimal(20,6),
+    CONSTRAINT user_decimal_entity_id_user_entity_id FOREIGN KEY (`entity_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY user_decimal_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `user_datetime` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  datetime(3),
+    CONSTRAINT user_datetime_entity_id_user_entity_id FOREIGN KEY (`entity_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY user_datetime_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `user_text` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  mediumtext,
+    CONSTRAINT user_text_entity_id_user_entity_id FOREIGN KEY (`entity_id`) REFERENCES `user`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY user_text_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `product_int` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  bigint,
+    CONSTRAINT product_int_entity_id_product_entity_id FOREIGN KEY (`entity_id`) REFERENCES `product`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY product_int_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `product_decimal` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  decimal(20,6),
+    CONSTRAINT product_decimal_entity_id_product_entity_id FOREIGN KEY (`entity_id`) REFERENCES `product`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY product_decimal_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `product_datetime` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  datetime(3),
+    CONSTRAINT product_datetime_entity_id_product_entity_id FOREIGN KEY (`entity_id`) REFERENCES `product`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY product_datetime_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+ CREATE TABLE IF NOT EXISTS `product_text` (
+   `entity_id` bigint unsigned NOT NULL,
+   `locale` varchar(255),
+   `attribute_name` varchar(255),
+   `value`  mediumtext,
+    CONSTRAINT product_text_entity_id_product_entity_id FOREIGN KEY (`entity_id`) REFERENCES `product`(`entity_id`) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE KEY product_text_entity_id_locale_value (`entity_id`,`locale`,`attribute_name`)
+ ) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
@@ -0,0 +1,64 @@
+ALTER TABLE `user_varchar`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY user_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX service_varchar_entity_id_locale_value;
+
+ALTER TABLE `product_varchar`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY product_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_varchar_entity_id_locale_value;
+
+ALTER TABLE `user_int`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY user_int_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_int_entity_id_locale_value;
+
+ALTER TABLE `user_decimal`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY user_decimal_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_decimal_entity_id_locale_value;
+
+ALTER TABLE `user_datetime`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY user_datetime_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_datetime_entity_id_locale_value;
+
+ALTER TABLE `user_text`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY user_text_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_text_entity_id_locale_value;
+
+ALTER TABLE `product_int`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY product_int_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_int_entity_id_locale_value;
+
+ALTER TABLE `product_decimal`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY product_decimal_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_decimal_entity_id_locale_value;
+
+ALTER TABLE `product_datetime`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY product_datetime_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_datetime_entity_id_locale_value;
+
+ALTER TABLE `product_text`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY product_text_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_text_entity_id_locale_value;
+
+ALTER TABLE `address_varchar`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY address_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX address_varchar_entity_id_locale_value;
+
+ALTER TABLE `role_varchar`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY role_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX role_varchar_entity_id_locale_value;
+
+ALTER TABLE `category_varchar`
+  ADD COLUMN `position` int not null default 0,
+  ADD UNIQUE KEY category_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX category_varchar_entity_id_locale_value;
@@ -0,0 +1,38 @@
+ALTER TABLE `product_text`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `product_datetime`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `product_decimal`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `product_int`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `product_varchar`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `user_text`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `user_datetime`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `user_decimal`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `user_int`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `user_varchar`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `category_varchar`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `role_varchar`
+  DROP COLUMN `updated_at`;
+
+ALTER TABLE `address_varchar`
+  DROP COLUMN `updated_at`;
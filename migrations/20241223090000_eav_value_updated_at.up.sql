@@ -0,0 +1,38 @@
+ALTER TABLE `address_varchar`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `role_varchar`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `category_varchar`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `user_varchar`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `user_int`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `user_decimal`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `user_datetime`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `user_text`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `product_varchar`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `product_int`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `product_decimal`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `product_datetime`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
+
+ALTER TABLE `product_text`
+  ADD COLUMN `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3);
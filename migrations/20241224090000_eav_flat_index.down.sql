@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `eav_flat_index`;
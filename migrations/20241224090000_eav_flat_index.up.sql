@@ -0,0 +1,9 @@
+  CREATE TABLE IF NOT EXISTS `eav_flat_index` (
+  `entity_type` varchar(64) not null,
+  `locale` varchar(255) not null,
+  `default_locale` varchar(255) not null,
+  `signature` varchar(32) not null,
+  `is_valid` tinyint(1) not null default 0,
+  `updated_at` datetime(3) not null default current_timestamp(3) on update current_timestamp(3),
+   PRIMARY KEY (`entity_type`,`locale`)
+) ENGINE=InnoDB  DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,2 @@
+ALTER TABLE `address`
+  DROP INDEX address_postcode_fulltext;
@@ -0,0 +1,2 @@
+ALTER TABLE `address`
+  ADD FULLTEXT KEY address_postcode_fulltext (`postcode`);
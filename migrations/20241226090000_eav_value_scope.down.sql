@@ -0,0 +1,90 @@
+UPDATE `category_varchar` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `category_varchar`
+  ADD UNIQUE KEY category_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX category_varchar_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `role_varchar` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `role_varchar`
+  ADD UNIQUE KEY role_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX role_varchar_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `address_varchar` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `address_varchar`
+  ADD UNIQUE KEY address_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX address_varchar_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `product_text` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `product_text`
+  ADD UNIQUE KEY product_text_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_text_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `product_datetime` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `product_datetime`
+  ADD UNIQUE KEY product_datetime_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_datetime_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `product_decimal` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `product_decimal`
+  ADD UNIQUE KEY product_decimal_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_decimal_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `product_int` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `product_int`
+  ADD UNIQUE KEY product_int_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_int_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `product_varchar` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `product_varchar`
+  ADD UNIQUE KEY product_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX product_varchar_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `user_text` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `user_text`
+  ADD UNIQUE KEY user_text_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_text_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `user_datetime` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `user_datetime`
+  ADD UNIQUE KEY user_datetime_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_datetime_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `user_decimal` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `user_decimal`
+  ADD UNIQUE KEY user_decimal_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_decimal_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `user_int` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `user_int`
+  ADD UNIQUE KEY user_int_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_int_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
+
+UPDATE `user_varchar` SET `locale` = concat(`locale`, '@', `scope`) WHERE `scope` <> '';
+
+ALTER TABLE `user_varchar`
+  ADD UNIQUE KEY user_varchar_entity_id_locale_attribute_position (`entity_id`,`locale`,`attribute_name`,`position`),
+  DROP INDEX user_varchar_entity_id_locale_scope_attribute_position,
+  DROP COLUMN `scope`;
@@ -0,0 +1,90 @@
+ALTER TABLE `user_varchar`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY user_varchar_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX user_varchar_entity_id_locale_attribute_position;
+
+UPDATE `user_varchar` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `user_int`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY user_int_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX user_int_entity_id_locale_attribute_position;
+
+UPDATE `user_int` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `user_decimal`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY user_decimal_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX user_decimal_entity_id_locale_attribute_position;
+
+UPDATE `user_decimal` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `user_datetime`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY user_datetime_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX user_datetime_entity_id_locale_attribute_position;
+
+UPDATE `user_datetime` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `user_text`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY user_text_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX user_text_entity_id_locale_attribute_position;
+
+UPDATE `user_text` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `product_varchar`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY product_varchar_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX product_varchar_entity_id_locale_attribute_position;
+
+UPDATE `product_varchar` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `product_int`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY product_int_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX product_int_entity_id_locale_attribute_position;
+
+UPDATE `product_int` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `product_decimal`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY product_decimal_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX product_decimal_entity_id_locale_attribute_position;
+
+UPDATE `product_decimal` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `product_datetime`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY product_datetime_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX product_datetime_entity_id_locale_attribute_position;
+
+UPDATE `product_datetime` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `product_text`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY product_text_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX product_text_entity_id_locale_attribute_position;
+
+UPDATE `product_text` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `address_varchar`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY address_varchar_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX address_varchar_entity_id_locale_attribute_position;
+
+UPDATE `address_varchar` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `role_varchar`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY role_varchar_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX role_varchar_entity_id_locale_attribute_position;
+
+UPDATE `role_varchar` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';
+
+ALTER TABLE `category_varchar`
+  ADD COLUMN `scope` varchar(64) not null default '' AFTER `locale`,
+  ADD UNIQUE KEY category_varchar_entity_id_locale_scope_attribute_position (`entity_id`,`locale`,`scope`,`attribute_name`,`position`),
+  DROP INDEX category_varchar_entity_id_locale_attribute_position;
+
+UPDATE `category_varchar` SET `scope` = substring_index(`locale`, '@', -1), `locale` = substring_index(`locale`, '@', 1) WHERE `locale` like '%@%';